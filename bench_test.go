@@ -3,6 +3,7 @@ package csve
 import (
 	"encoding/csv"
 	"encoding/json"
+	"io"
 	"strconv"
 	"testing"
 	"time"
@@ -121,3 +122,79 @@ func BenchmarkEncode(b *testing.B) {
 		enc.Encode(&d)
 	}
 }
+
+func BenchmarkDecodeTokenizer(b *testing.B) {
+	type data struct {
+		V1 string    `csv:"0,v1"`
+		V2 int64     `csv:"1,v2"`
+		V3 float64   `csv:"2,v3"`
+		V4 time.Time `csv:"3,v4,2006-01-02T15:04:05Z07:00"`
+	}
+	r := NewTokenizer(&benchCsvSrcReader{[]byte(`"str",1,2.0,2017-12-24T15:30:00Z` + "\n")})
+	dec, _ := NewDecoder(r, false)
+
+	var d data
+	for i := 0; i < b.N; i++ {
+		dec.Decode(&d)
+	}
+}
+
+func BenchmarkDecodeTokenizerNumbers(b *testing.B) {
+	type data struct {
+		V1 int64   `csv:"0,v1"`
+		V2 int32   `csv:"1,v2"`
+		V3 float64 `csv:"2,v3"`
+		V4 uint64  `csv:"3,v4"`
+	}
+	r := NewTokenizer(&benchCsvSrcReader{[]byte("1,2,3.5,4\n")})
+	dec, _ := NewDecoder(r, false)
+
+	var d data
+	for i := 0; i < b.N; i++ {
+		dec.Decode(&d)
+	}
+}
+
+func BenchmarkDecodeIntoTokenizer(b *testing.B) {
+	type data struct {
+		V1 string `csv:"0,v1"`
+		V2 string `csv:"1,v2"`
+		V3 int64  `csv:"2,v3"`
+	}
+	r := NewTokenizer(&benchCsvSrcReader{[]byte("shipped,JP,1\n")})
+	dec, _ := NewDecoder(r, false)
+
+	var d data
+	for i := 0; i < b.N; i++ {
+		dec.DecodeInto(&d)
+	}
+}
+
+func BenchmarkParallelDecode(b *testing.B) {
+	type data struct {
+		V1 string    `csv:"0,v1"`
+		V2 int64     `csv:"1,v2"`
+		V3 float64   `csv:"2,v3"`
+		V4 time.Time `csv:"3,v4,2006-01-02T15:04:05Z07:00"`
+	}
+	r := newBenchCsvReader(`"str",1,2.0,2017-12-24T15:30:00Z` + "\n")
+	dec, _ := NewDecoder(&limitReader{r, b.N}, false)
+	pd := NewParallelDecoder(dec, func() interface{} { return new(data) })
+
+	for range pd.Decode() {
+	}
+}
+
+// limitReader reads n records.
+type limitReader struct {
+	CsvReader
+	n int
+}
+
+func (r *limitReader) Read() ([]string, error) {
+	if r.n <= 0 {
+		return nil, io.EOF
+	}
+	r.n--
+	return r.CsvReader.Read()
+}
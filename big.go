@@ -0,0 +1,165 @@
+package csve
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// addr returns pointer to v. If v is not addressable, it returns pointer to a
+// copy of v.
+func addr(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v.Addr()
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p
+}
+
+// normalizeDecimal converts raw into plain decimal number based on number
+// format. If pct is true, the parsed number must be divided by 100.
+func normalizeDecimal(raw string, nf *numberFormat) (s string, pct bool, err error) {
+	if nf == nil {
+		return raw, false, nil
+	}
+	return nf.normalize(raw)
+}
+
+func bigIntDecoder(opts *tagOptions) fieldDecoder {
+	nf, is := opts.num, opts.ints
+	base := is.radix()
+	return func(d *Decoder, v reflect.Value, raw, format string) error {
+		s, err := normalizeInt(raw, nf, is)
+		if err != nil {
+			return err
+		}
+		if _, ok := v.Addr().Interface().(*big.Int).SetString(s, base); !ok {
+			return errors.New("invalid big.Int value")
+		}
+		return nil
+	}
+}
+
+func bigIntEncoder(opts *tagOptions) fieldEncoder {
+	nf, is := opts.num, opts.ints
+	base := is.radix()
+	return func(e *Encoder, v reflect.Value, format string) (string, error) {
+		n := addr(v).Interface().(*big.Int)
+		return formatInt(n.Text(base), nf, is), nil
+	}
+}
+
+func bigFloatDecoder(opts *tagOptions) fieldDecoder {
+	nf, bits := opts.num, opts.bits
+	return func(d *Decoder, v reflect.Value, raw, format string) error {
+		f := v.Addr().Interface().(*big.Float)
+		if bits > 0 {
+			f.SetPrec(bits)
+		}
+		s, pct, err := normalizeDecimal(raw, nf)
+		if err != nil {
+			return err
+		}
+		if !pct {
+			if _, ok := f.SetString(s); !ok {
+				return errors.New("invalid big.Float value")
+			}
+			return nil
+		}
+
+		// parse with extra precision so the quotient is rounded once
+		prec := f.Prec()
+		if prec == 0 {
+			prec = 64
+		}
+		n, ok := new(big.Float).SetPrec(prec + 64).SetString(s)
+		if !ok {
+			return errors.New("invalid big.Float value")
+		}
+		f.SetPrec(prec).Quo(n, big.NewFloat(100))
+		return nil
+	}
+}
+
+func bigFloatEncoder(opts *tagOptions) fieldEncoder {
+	nf := opts.num
+	return func(e *Encoder, v reflect.Value, format string) (string, error) {
+		s := addr(v).Interface().(*big.Float).Text('f', -1)
+		if nf != nil {
+			s = nf.format(s)
+		}
+		return s, nil
+	}
+}
+
+func bigRatDecoder(opts *tagOptions) fieldDecoder {
+	nf := opts.num
+	return func(d *Decoder, v reflect.Value, raw, format string) error {
+		s, pct, err := normalizeDecimal(raw, nf)
+		if err != nil {
+			return err
+		}
+		r := v.Addr().Interface().(*big.Rat)
+		if _, ok := r.SetString(s); !ok {
+			return errors.New("invalid big.Rat value")
+		}
+		if pct {
+			r.Quo(r, big.NewRat(100, 1))
+		}
+		return nil
+	}
+}
+
+func bigRatEncoder(opts *tagOptions) fieldEncoder {
+	nf := opts.num
+	return func(e *Encoder, v reflect.Value, format string) (string, error) {
+		r := addr(v).Interface().(*big.Rat)
+		if n, exact := r.FloatPrec(); exact {
+			s := r.FloatString(n)
+			if nf != nil {
+				s = nf.format(s)
+			}
+			return s, nil
+		}
+		// a fraction like 1/3 has no finite decimal representation
+		if nf == nil || nf.precision < 0 {
+			return r.RatString(), nil
+		}
+		scale := nf.precision
+		if nf.percent {
+			scale += 2
+		}
+		num := new(big.Int).Mul(r.Num(), pow10(scale))
+		return nf.format(formatScaled(roundDiv(num, r.Denom(), nf.round), scale)), nil
+	}
+}
+
+func complexDecoder(d *Decoder, v reflect.Value, raw, format string) error {
+	c, err := strconv.ParseComplex(raw, v.Type().Bits())
+	if err != nil && !isRangeError(err) {
+		return err
+	}
+	if err != nil || v.OverflowComplex(c) {
+		return &RangeError{Raw: raw, Type: v.Type()}
+	}
+	v.SetComplex(c)
+	return nil
+}
+
+func complexEncoder(opts *tagOptions) fieldEncoder {
+	prec := -1
+	if opts.num != nil {
+		prec = opts.num.precision
+	}
+	return func(e *Encoder, v reflect.Value, format string) (string, error) {
+		return strconv.FormatComplex(v.Complex(), 'f', prec, v.Type().Bits()), nil
+	}
+}
@@ -0,0 +1,123 @@
+package csve
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+type TestBigData struct {
+	Int     *big.Int   `csv:"0,int"`
+	Float   *big.Float `csv:"1,float,,bits=128"`
+	Rat     big.Rat    `csv:"2,rat"`
+	Amount  *big.Rat   `csv:"3,amount,,thousands=,,precision=2,round=half_even"`
+	Complex complex128 `csv:"4,complex"`
+	Hex     *big.Int   `csv:"5,hex,,base=16,prefix"`
+}
+
+func TestBig_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "normal case",
+			raw:  `123456789012345678901234567890,12345678901234567890.125,1/3,"1,234.56",(1+2i),0xffffffffffffffffff` + "\n",
+			want: `123456789012345678901234567890,12345678901234567890.125,1/3,"1,234.56",(1+2i),0xffffffffffffffffff` + "\n",
+		},
+		{
+			name: "rounding case",
+			raw:  `-1,0.5,12.30,0.125,(0-1.5i),-0x1` + "\n",
+			want: `-1,0.5,12.3,0.12,(0-1.5i),-0x1` + "\n",
+		},
+		{
+			name: "nil case",
+			raw:  `,,0,,0,` + "\n",
+			want: `,,0,,(0+0i),` + "\n",
+		},
+		{
+			name:    "invalid case",
+			raw:     `1.5,0,0,0,0,0` + "\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(tt.raw)), false)
+			var v TestBigData
+			if err := d.Decode(&v); (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			buf := new(bytes.Buffer)
+			e, _ := NewEncoder(csv.NewWriter(buf), false)
+			if err := e.Encode(v); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			e.Flush()
+			if buf.String() != tt.want {
+				t.Errorf("Encode() = %v, want %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_roundDiv(t *testing.T) {
+	tests := []struct {
+		num  int64
+		mode RoundingMode
+		want int64
+	}{
+		{25, RoundHalfUp, 3},
+		{-25, RoundHalfUp, -3},
+		{25, RoundHalfDown, 2},
+		{25, RoundHalfEven, 2},
+		{35, RoundHalfEven, 4},
+		{21, RoundUp, 3},
+		{29, RoundDown, 2},
+		{-21, RoundCeiling, -2},
+		{-21, RoundFloor, -3},
+	}
+	for _, tt := range tests {
+		got := roundDiv(big.NewInt(tt.num), big.NewInt(10), tt.mode)
+		if got.Int64() != tt.want {
+			t.Errorf("roundDiv(%d, 10, %d) = %v, want %v", tt.num, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestBig_percent(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"12.5%", "0.125"},
+		{"1.5e1%", "0.15"},
+		{"-2E-1%", "-0.002"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			raw := strings.Repeat(tt.raw+",", 2) + tt.raw + "\n"
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(raw)), false)
+			var v struct {
+				Decimal Decimal    `csv:"0,decimal,,percent"`
+				Float   *big.Float `csv:"1,float,,percent"`
+				Rat     *big.Rat   `csv:"2,rat,,percent"`
+			}
+			if err := d.Decode(&v); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			want, _ := new(big.Rat).SetString(tt.want)
+			if v.Decimal.Rat().Cmp(want) != 0 || v.Float.Text('g', 10) != tt.want || v.Rat.Cmp(want) != 0 {
+				t.Errorf("Decode() = %v, %v, %v, want %v", v.Decimal, v.Float, v.Rat, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,524 @@
+package csve
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"runtime"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// ChunkDecoder decodes a csv file in parallel by splitting it into byte
+// ranges aligned to record boundaries. Each chunk is parsed and decoded by
+// its own csv reader, so both parsing and decoding use all cores, unlike
+// ParallelDecoder which parses on one goroutine.
+//
+// Boundaries are found by a sequential scan which tracks quotes, so a
+// newline in a quoted field does not split a record. The scan only looks for
+// delimiters, quotes and newlines, which is much faster than parsing.
+//
+//	f, _ := os.Open("orders.csv")
+//	fi, _ := f.Stat()
+//	cd, err := csve.NewChunkDecoder(f, fi.Size(), csve.Dialect{Header: true},
+//		func() interface{} { return new(Order) })
+//	for r := range cd.Decode() {
+//		...
+//	}
+type ChunkDecoder struct {
+	// Workers is the number of goroutines decoding chunks. Default is
+	// GOMAXPROCS.
+	Workers int
+
+	// ChunkSize is the approximate size of a chunk in bytes. Default is 4MiB.
+	ChunkSize int64
+
+	// If Unordered is true, results of a chunk are sent as soon as the chunk
+	// is decoded instead of in the order of lines.
+	Unordered bool
+
+	// Setup, if not nil, is called for the Decoder of each chunk to set up
+	// options like Location and NullValues. The Decoder decodes only its
+	// chunk, so Records, Skipped and OnProgress are not useful. Count
+	// Result.Err instead.
+	Setup func(d *Decoder)
+
+	r        io.ReaderAt
+	size     int64
+	dialect  Dialect
+	newValue func() interface{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+const defaultChunkSize = 4 << 20
+
+// chunk is a byte range of csv.
+type chunk struct {
+	seq    int
+	offset int64
+	size   int64
+	line   int   // physical lines before the chunk
+	text   int64 // offset of the chunk in the text after the BOM
+	fields int   // FieldsPerRecord of the csv reader
+	err    error
+}
+
+// NewChunkDecoder returns a ChunkDecoder which reads size bytes from r.
+// newValue returns a new pointer to a struct for each record.
+// The delimiter, quote and comment character of dialect must be ASCII, and
+// UTF-16 is not supported.
+func NewChunkDecoder(r io.ReaderAt, size int64, dialect Dialect, newValue func() interface{}) (*ChunkDecoder, error) {
+	if err := dialect.validate(); err != nil {
+		return nil, err
+	}
+	if dialect.Comma >= 0x80 || dialect.Comment >= 0x80 {
+		return nil, errors.New("delimiter and comment must be ASCII to split csv by bytes")
+	}
+	switch dialect.Charset {
+	case UTF16LE, UTF16BE:
+		return nil, errors.New("UTF-16 is not supported to split csv by bytes")
+	case ShiftJIS:
+		// trail bytes of Shift_JIS are in 0x40-0xFC
+		if dialect.Comma >= 0x40 || dialect.Comment >= 0x40 || dialect.quote() >= 0x40 {
+			return nil, errors.New("delimiter, quote and comment must be less than 0x40 for Shift_JIS")
+		}
+	}
+
+	return &ChunkDecoder{
+		r:        r,
+		size:     size,
+		dialect:  dialect,
+		newValue: newValue,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Decode starts decoding and returns the channel of results, which is closed
+// after all records are decoded. Decode must be called once. Call Close to
+// stop before the channel is closed.
+func (c *ChunkDecoder) Decode() <-chan Result {
+	workers := c.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	out := make(chan Result, parallelBatch)
+
+	plan, err := newValuePlan(c.newValue)
+	if err != nil {
+		out <- Result{Err: err}
+		close(out)
+		return out
+	}
+
+	chunks := make(chan chunk, workers)
+	decoded := make(chan parallelResult, workers)
+	// results of a chunk are kept until sent, so limit chunks in flight
+	window := make(chan struct{}, workers*2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ch := range chunks {
+				select {
+				case decoded <- parallelResult{seq: ch.seq, results: c.decodeChunk(ch, plan)}:
+				case <-c.done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(decoded)
+	}()
+	go c.split(chunks, window)
+	go collectResults(decoded, out, window, c.done, c.Unordered)
+	return out
+}
+
+// Close stops decoding. Results not received yet are discarded.
+func (c *ChunkDecoder) Close() {
+	c.once.Do(func() { close(c.done) })
+}
+
+func (c *ChunkDecoder) split(chunks chan<- chunk, window chan struct{}) {
+	defer close(chunks)
+
+	chunkSize := c.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	send := func(ch chunk) bool {
+		select {
+		case window <- struct{}{}:
+		case <-c.done:
+			return false
+		}
+		select {
+		case chunks <- ch:
+			return true
+		case <-c.done:
+			return false
+		}
+	}
+
+	s := newRecordScanner(c.dialect)
+	seq := 0
+	start, bom := int64(0), int64(0)
+	startLine := 0
+	emit := func(end int64) bool {
+		ch := chunk{
+			seq:    seq,
+			offset: start,
+			size:   end - start,
+			line:   startLine,
+			fields: c.dialect.FieldsPerRecord,
+		}
+		if seq > 0 {
+			ch.text = start - bom
+		}
+		if seq > 0 && ch.fields == 0 {
+			ch.fields = s.firstFields
+		}
+		seq++
+		start, startLine = end, s.lines
+		return send(ch)
+	}
+
+	buf := make([]byte, 64*1024)
+	offset := int64(0)
+	for offset < c.size {
+		n := int64(len(buf))
+		if c.size-offset < n {
+			n = c.size - offset
+		}
+		n2, err := c.r.ReadAt(buf[:n], offset)
+		if int64(n2) < n {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			send(chunk{seq: seq, err: err, line: s.lines})
+			return
+		}
+
+		b := buf[:n]
+		if offset == 0 && bytes.HasPrefix(b, utf8BOM) {
+			b = b[len(utf8BOM):]
+			bom = int64(len(utf8BOM))
+		}
+		base := offset + n - int64(len(b))
+		for i := 0; i < len(b); {
+			k := s.scan(b[i:])
+			i += k
+			if s.ended {
+				end := base + int64(i)
+				if end-start >= chunkSize && !emit(end) {
+					return
+				}
+			}
+		}
+		offset += n
+	}
+	if start < c.size {
+		emit(c.size)
+	}
+}
+
+func (c *ChunkDecoder) decodeChunk(ch chunk, plan *plan) []Result {
+	if ch.err != nil {
+		return []Result{{Line: ch.line + 1, Err: ch.err}}
+	}
+
+	r, err := newTextReader(io.NewSectionReader(c.r, ch.offset, ch.size), c.dialect.Charset)
+	if err != nil {
+		return []Result{{Line: ch.line + 1, Err: err}}
+	}
+	dl := c.dialect
+	dl.FieldsPerRecord = ch.fields
+	cr := dl.newCsvReader(r)
+
+	d, _ := NewDecoder(cr, false)
+	if c.Setup != nil {
+		c.Setup(d)
+	}
+	// positions in the chunk are converted into those in the file
+	d.line, d.lineBase, d.offsetBase = ch.line, ch.line, ch.text
+	if c.dialect.Charset.encoding() != nil {
+		// offsets are in UTF-8 after transcoding, unknown for the chunk
+		d.offsetBase = -1
+	}
+
+	var results []Result
+	if ch.seq == 0 && c.dialect.Header {
+		if err := d.skipHeader(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return []Result{{Line: d.line + 1, Err: chunkError(err, ch)}}
+		}
+	}
+	for {
+		cols, err := d.readRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// a reading error ends the chunk
+			return append(results, Result{Line: d.line, Err: chunkError(err, ch)})
+		}
+
+		v := c.newValue()
+		err = d.decodeRecord(plan, v, cols)
+		results = append(results, Result{Value: v, Line: d.line, Err: err})
+	}
+	return results
+}
+
+// chunkError converts line numbers of csv.ParseError into those of the file.
+func chunkError(err error, ch chunk) error {
+	if perr, ok := err.(*csv.ParseError); ok {
+		perr.StartLine += ch.line
+		perr.Line += ch.line
+	}
+	return err
+}
+
+// recordScanner finds record boundaries in csv bytes. It tracks quotes the
+// same way as encoding/csv, so it can start only at the beginning of csv.
+type recordScanner struct {
+	comma, quote, comment byte
+	trimLeadingSpace      bool
+	charset               Charset
+
+	state       scanState
+	ended       bool // a record ended at the last byte scanned
+	records     int
+	lines       int
+	fields      int
+	firstFields int
+
+	// char holds bytes of a non-ASCII character at the start of a field,
+	// which is trimmed if it is a space.
+	char []byte
+}
+
+type scanState int
+
+const (
+	scanLineStart   scanState = iota
+	scanLineStartCR           // after \r at the beginning of a line
+	scanComment
+	scanFieldStart
+	scanUnquoted
+	scanQuoted
+	scanQuoteEnd   // after a quote in a quoted field
+	scanQuoteEndCR // after \r following a quote in a quoted field
+)
+
+func newRecordScanner(dl Dialect) *recordScanner {
+	return &recordScanner{
+		comma:            byte(dl.comma()),
+		quote:            dl.quote(),
+		comment:          byte(dl.Comment),
+		trimLeadingSpace: dl.TrimLeadingSpace,
+		charset:          dl.Charset,
+	}
+}
+
+// scan scans b until a record ends, and returns the number of bytes scanned.
+// If the record ended, s.ended is true.
+func (s *recordScanner) scan(b []byte) int {
+	s.ended = false
+	for i, c := range b {
+		if c == '\n' {
+			s.lines++
+		}
+		for s.step(c) {
+		}
+		if s.ended {
+			return i + 1
+		}
+	}
+	return len(b)
+}
+
+// step advances the state by c. It returns true if c has to be scanned
+// again in the new state.
+func (s *recordScanner) step(c byte) bool {
+	if len(s.char) > 0 {
+		if !s.isTrail(c) {
+			// an invalid character is not a space
+			s.char = s.char[:0]
+			s.state = scanUnquoted
+			return true
+		}
+		s.char = append(s.char, c)
+		s.trimChar()
+		return false
+	}
+
+	switch s.state {
+	case scanLineStart:
+		switch {
+		case c == '\n':
+			// empty line
+		case c == '\r':
+			s.state = scanLineStartCR
+		case s.comment != 0 && c == s.comment:
+			s.state = scanComment
+		default:
+			s.fieldStart(c)
+		}
+	case scanLineStartCR:
+		if c == '\n' {
+			// \r\n is an empty line
+			s.state = scanLineStart
+			return false
+		}
+		// \r not followed by \n is data
+		s.fieldStart('\r')
+		return true
+	case scanComment:
+		if c == '\n' {
+			s.state = scanLineStart
+		}
+	case scanFieldStart:
+		s.fieldStart(c)
+	case scanUnquoted:
+		switch c {
+		case s.comma:
+			s.fields++
+			s.state = scanFieldStart
+		case '\n':
+			s.endRecord()
+		}
+	case scanQuoted:
+		if c == s.quote {
+			s.state = scanQuoteEnd
+		}
+	case scanQuoteEnd:
+		switch c {
+		case s.quote:
+			s.state = scanQuoted
+		case s.comma:
+			s.fields++
+			s.state = scanFieldStart
+		case '\n':
+			s.endRecord()
+		case '\r':
+			s.state = scanQuoteEndCR
+		default:
+			// a bare quote with LazyQuotes
+			s.state = scanQuoted
+		}
+	case scanQuoteEndCR:
+		if c == '\n' {
+			s.endRecord()
+			return false
+		}
+		// \r not followed by \n is data after a bare quote
+		s.state = scanQuoted
+		return true
+	}
+	return false
+}
+
+func (s *recordScanner) fieldStart(c byte) {
+	switch {
+	case c == s.quote:
+		s.state = scanQuoted
+	case c == s.comma:
+		s.fields++
+		s.state = scanFieldStart
+	case c == '\n':
+		s.endRecord()
+	case s.trimLeadingSpace && c < utf8.RuneSelf && unicode.IsSpace(rune(c)):
+		s.state = scanFieldStart
+	case s.trimLeadingSpace && c >= utf8.RuneSelf:
+		s.state = scanFieldStart
+		s.char = append(s.char[:0], c)
+		s.trimChar()
+	default:
+		s.state = scanUnquoted
+	}
+}
+
+// trimChar decides whether the character in s.char is leading space like
+// csv.Reader does with unicode.IsSpace, once all its bytes are scanned.
+func (s *recordScanner) trimChar() {
+	if len(s.char) < charLen(s.charset, s.char[0]) {
+		return
+	}
+	r := decodeChar(s.charset, s.char)
+	s.char = s.char[:0]
+	if !unicode.IsSpace(r) {
+		s.state = scanUnquoted
+	}
+}
+
+// isTrail reports whether c can follow the bytes in s.char in a character.
+func (s *recordScanner) isTrail(c byte) bool {
+	switch s.charset {
+	case ShiftJIS:
+		return c >= 0x40 && c <= 0xFC && c != 0x7F
+	case EUCJP:
+		return c >= 0xA1 && c <= 0xFE
+	}
+	return c&0xC0 == 0x80
+}
+
+// charLen returns the length of the character beginning with c in charset.
+func charLen(charset Charset, c byte) int {
+	switch charset {
+	case ShiftJIS:
+		if (c >= 0x81 && c <= 0x9F) || (c >= 0xE0 && c <= 0xFC) {
+			return 2
+		}
+	case EUCJP:
+		switch {
+		case c == 0x8F:
+			return 3
+		case c == 0x8E, c >= 0xA1 && c <= 0xFE:
+			return 2
+		}
+	default:
+		switch {
+		case c&0xE0 == 0xC0:
+			return 2
+		case c&0xF0 == 0xE0:
+			return 3
+		case c&0xF8 == 0xF0:
+			return 4
+		}
+	}
+	return 1
+}
+
+// decodeChar returns the character b in charset as a rune.
+func decodeChar(charset Charset, b []byte) rune {
+	if enc := charset.encoding(); enc != nil {
+		var err error
+		if b, err = enc.NewDecoder().Bytes(b); err != nil {
+			return utf8.RuneError
+		}
+	}
+	r, _ := utf8.DecodeRune(b)
+	return r
+}
+
+func (s *recordScanner) endRecord() {
+	if s.records == 0 {
+		s.firstFields = s.fields + 1
+	}
+	s.records++
+	s.fields = 0
+	s.state = scanLineStart
+	s.ended = true
+}
@@ -0,0 +1,200 @@
+package csve
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testChunkCsv(n int) string {
+	var b strings.Builder
+	b.WriteString("\xEF\xBB\xBFid,name\r\n")
+	for i := 0; i < n; i++ {
+		switch i % 5 {
+		case 0:
+			fmt.Fprintf(&b, "%d,\"multi\nline %d\"\n", i, i)
+		case 1:
+			fmt.Fprintf(&b, "%d,\"quote \"\"%d\"\",\"\r\n", i, i)
+		case 2:
+			fmt.Fprintf(&b, "# comment \" %d\n\n%d,name%d\n", i, i, i)
+		default:
+			fmt.Fprintf(&b, "%d,name%d\n", i, i)
+		}
+	}
+	return b.String()
+}
+
+func TestChunkDecoder(t *testing.T) {
+	raw := testChunkCsv(1000)
+	dialect := Dialect{Header: true, Comment: '#'}
+
+	// expected by sequential decoding
+	d, _ := NewDecoderFromReader(strings.NewReader(raw), dialect)
+	var want []Result
+	for {
+		v := new(testRecord)
+		if err := d.Decode(v); err != nil {
+			break
+		}
+		want = append(want, Result{Value: v, Line: d.line})
+	}
+
+	for _, chunkSize := range []int64{1, 100, 1000, 1 << 20} {
+		t.Run(fmt.Sprint(chunkSize), func(t *testing.T) {
+			cd, err := NewChunkDecoder(strings.NewReader(raw), int64(len(raw)), dialect,
+				func() interface{} { return new(testRecord) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			cd.ChunkSize = chunkSize
+			cd.Workers = 4
+
+			var got []Result
+			for r := range cd.Decode() {
+				if r.Err != nil {
+					t.Fatalf("Result.Err = %v", r.Err)
+				}
+				got = append(got, r)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Decode() got %d results, want %d", len(got), len(want))
+				for i := range got {
+					if i < len(want) && !reflect.DeepEqual(got[i], want[i]) {
+						t.Fatalf("Decode()[%d] = %+v, want %+v", i, got[i], want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestChunkDecoder_error(t *testing.T) {
+	raw := "1,a\n2,b\n\"3\nx\"y,c\n4,d\n"
+	cd, _ := NewChunkDecoder(strings.NewReader(raw), int64(len(raw)), Dialect{},
+		func() interface{} { return new(testRecord) })
+	cd.ChunkSize = 1
+
+	var errs []error
+	for r := range cd.Decode() {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	var perr *csv.ParseError
+	if len(errs) != 1 || !errors.As(errs[0], &perr) || perr.StartLine != 3 {
+		t.Errorf("Decode() errors = %v, want ParseError at line 3", errs)
+	}
+}
+
+func TestChunkDecoder_position(t *testing.T) {
+	raw := testChunkCsv(100) + "x,a\n" + testChunkCsv(100)[len("\xEF\xBB\xBFid,name\r\n"):] + "y,\"b\nc\"\n"
+	dialect := Dialect{Header: true, Comment: '#'}
+
+	// expected by sequential decoding
+	d, _ := NewDecoderFromReader(strings.NewReader(raw), dialect)
+	var want []error
+	for {
+		err := d.Decode(new(testRecord))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			want = append(want, err)
+		}
+	}
+
+	cd, _ := NewChunkDecoder(strings.NewReader(raw), int64(len(raw)), dialect,
+		func() interface{} { return new(testRecord) })
+	cd.ChunkSize = 100
+	var got []error
+	for r := range cd.Decode() {
+		if r.Err != nil {
+			got = append(got, r.Err)
+		}
+	}
+	if len(want) != 2 || !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() errors = %v, want %v", got, want)
+	}
+}
+
+type testChunkCells struct {
+	A string `csv:"0,a"`
+	B string `csv:"1,b"`
+}
+
+// TestChunkDecoder_boundary checks that chunks split records where
+// csv.Reader does.
+func TestChunkDecoder_boundary(t *testing.T) {
+	trim := Dialect{TrimLeadingSpace: true, FieldsPerRecord: -1}
+	lazy := Dialect{LazyQuotes: true, FieldsPerRecord: -1}
+	tests := []struct {
+		name    string
+		dialect Dialect
+		raw     string
+	}{
+		{"trimmed \\r", trim, "x\n \r\"a\nb\"\n"},
+		{"trimmed \\v\\f", trim, "x\n\v\f\"a\nb\"\n"},
+		{"trimmed U+0085", trim, "x\n\u0085\"a\nb\"\n"},
+		{"trimmed U+00A0", trim, "x\n,\u00a0\"a\nb\"\n"},
+		{"trimmed U+3000", trim, "x\n\u3000\"a\nb\"\n"},
+		{"not trimmed", Dialect{TrimLeadingSpace: true, LazyQuotes: true, FieldsPerRecord: -1}, "x\n\u00e0\"a\nb\"\n"},
+		{"trimmed Shift_JIS", Dialect{TrimLeadingSpace: true, FieldsPerRecord: -1, Charset: ShiftJIS}, "x\n\x81\x40\"a\nb\"\n"},
+		{"not trimmed Shift_JIS", Dialect{TrimLeadingSpace: true, LazyQuotes: true, FieldsPerRecord: -1, Charset: ShiftJIS}, "x\n\x82\xa0\"a\nb\"\n"},
+		{"\\r at line start", lazy, "x\n\r\"a\nb\"\n"},
+		{"\\r\\n empty line", lazy, "x\n\r\n\"a\nb\"\n"},
+		{"\\r after quote", lazy, "x\n\"a\"\rb\nc\",d\n"},
+		{"\\r\\n after quote", lazy, "x\n\"a\"\r\nb\nc\",d\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// expected by sequential decoding
+			d, _ := NewDecoderFromReader(strings.NewReader(tt.raw), tt.dialect)
+			var want []Result
+			for {
+				v := new(testChunkCells)
+				err := d.Decode(v)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				want = append(want, Result{Value: v, Line: d.line})
+			}
+
+			cd, err := NewChunkDecoder(strings.NewReader(tt.raw), int64(len(tt.raw)), tt.dialect,
+				func() interface{} { return new(testChunkCells) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			cd.ChunkSize = 1
+			var got []Result
+			for r := range cd.Decode() {
+				if r.Err != nil {
+					t.Fatalf("Result.Err = %v", r.Err)
+				}
+				got = append(got, r)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Decode() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestNewChunkDecoder(t *testing.T) {
+	newValue := func() interface{} { return new(testRecord) }
+	for _, dl := range []Dialect{
+		{Comma: '、'},
+		{Charset: UTF16LE},
+		{Charset: ShiftJIS, Comma: '|'},
+	} {
+		if _, err := NewChunkDecoder(strings.NewReader(""), 0, dl, newValue); err == nil {
+			t.Errorf("NewChunkDecoder(%+v) error = nil, want error", dl)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+// Command csvegen generates DecodeCSV and EncodeCSV methods of structs from
+// their csv tags, which Decoder and Encoder of csve prefer to reflection.
+//
+//	//go:generate csvegen -type Order,Item
+//
+// Only files of the package declaring the types are parsed. Types declared in
+// _test.go files are found with -tests, which writes <type>_csve_test.go by
+// default.
+//
+// csvegen supports fields of basic types, named types of them and time.Time
+// without tag options. It fails for other fields, so reflection is never
+// silently mixed into generated code.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const csvePath = "github.com/yuichi1004/csve"
+
+var (
+	typeNames = flag.String("type", "", "comma separated list of struct type names; required")
+	output    = flag.String("output", "", "output file name; default <type>_csve.go")
+	tests     = flag.Bool("tests", false, "also parse _test.go files")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("csvegen: ")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: csvegen -type T[,T...] [-output file] [-tests] [dir]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	types := strings.Split(*typeNames, ",")
+
+	out := outputPath(dir, *output, types[0], *tests)
+
+	src, err := generate(dir, types, filepath.Base(out), *tests)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// outputPath returns the file generated code is written to. A relative
+// output is relative to dir, and an empty one defaults to <typ>_csve.go.
+func outputPath(dir, output, typ string, tests bool) string {
+	if output == "" {
+		output = strings.ToLower(typ) + "_csve.go"
+		if tests {
+			output = strings.ToLower(typ) + "_csve_test.go"
+		}
+	}
+	if filepath.IsAbs(output) {
+		return output
+	}
+	return filepath.Join(dir, output)
+}
+
+// kind is how a field is converted.
+type kind int
+
+const (
+	kindInt kind = iota
+	kindUint
+	kindFloat
+	kindBool
+	kindString
+	kindTime
+)
+
+var basicKinds = map[string]kind{
+	"int": kindInt, "int8": kindInt, "int16": kindInt, "int32": kindInt, "int64": kindInt,
+	"uint": kindUint, "uint8": kindUint, "uint16": kindUint, "uint32": kindUint, "uint64": kindUint,
+	"byte": kindUint, "rune": kindInt,
+	"float32": kindFloat, "float64": kindFloat,
+	"bool":   kindBool,
+	"string": kindString,
+}
+
+type genField struct {
+	name   string
+	index  int
+	format string
+	kind   kind
+	bits   int // for float
+}
+
+// generate returns source of DecodeCSV and EncodeCSV methods of types in the
+// package in dir. skip is the file name excluded from parsing, which is the
+// output file. _test.go files are parsed only if tests is true.
+func generate(dir string, types []string, skip string, tests bool) ([]byte, error) {
+	fset := token.NewFileSet()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	// type specs by package, since an external test package in the same
+	// directory may declare the same names
+	pkgSpecs := map[string]map[string]*ast.TypeSpec{}
+	for _, path := range paths {
+		base := filepath.Base(path)
+		if base == skip || (!tests && strings.HasSuffix(base, "_test.go")) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		specs := pkgSpecs[f.Name.Name]
+		if specs == nil {
+			specs = map[string]*ast.TypeSpec{}
+			pkgSpecs[f.Name.Name] = specs
+		}
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				specs[ts.Name.Name] = ts
+			}
+		}
+	}
+
+	// the package declaring the first type is the target
+	var pkgs []string
+	for name, specs := range pkgSpecs {
+		if _, ok := specs[types[0]]; ok {
+			pkgs = append(pkgs, name)
+		}
+	}
+	switch len(pkgs) {
+	case 0:
+		return nil, fmt.Errorf("type %s not found in %s", types[0], dir)
+	case 1:
+	default:
+		sort.Strings(pkgs)
+		return nil, fmt.Errorf("type %s is declared in packages %s", types[0], strings.Join(pkgs, ", "))
+	}
+	pkg := pkgs[0]
+	specs := pkgSpecs[pkg]
+
+	var body bytes.Buffer
+	usesStrconv := false
+	for _, name := range types {
+		ts, ok := specs[name]
+		if !ok {
+			return nil, fmt.Errorf("type %s not found in package %s", name, pkg)
+		}
+		st, ok := ts.Type.(*ast.StructType)
+		if !ok {
+			return nil, fmt.Errorf("type %s is not a struct", name)
+		}
+
+		fields, err := parseFields(st, specs)
+		if err != nil {
+			return nil, fmt.Errorf("type %s: %v", name, err)
+		}
+		for _, f := range fields {
+			usesStrconv = usesStrconv || f.kind != kindString && f.kind != kindTime
+		}
+		writeMethods(&body, name, fields)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by csvegen; DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	if usesStrconv {
+		fmt.Fprintln(&buf, `"strconv"`)
+		fmt.Fprintln(&buf)
+	}
+	fmt.Fprintf(&buf, "%q\n)\n", csvePath)
+	buf.Write(body.Bytes())
+	return format.Source(buf.Bytes())
+}
+
+// parseFields reads csv tags the same way as getFields of csve.
+func parseFields(st *ast.StructType, specs map[string]*ast.TypeSpec) ([]genField, error) {
+	var fields []genField
+	for _, f := range st.Fields.List {
+		if f.Tag == nil {
+			continue
+		}
+		tagValue, err := strconv.Unquote(f.Tag.Value)
+		if err != nil {
+			return nil, err
+		}
+		tag, ok := reflect.StructTag(tagValue).Lookup("csv")
+		if !ok {
+			continue
+		}
+		if len(f.Names) == 0 {
+			return nil, errors.New("embedded field is not supported")
+		}
+
+		tags := strings.Split(tag, ",")
+		if len(tags) < 2 {
+			return nil, fmt.Errorf("field %s: csv tag needs index and name", f.Names[0].Name)
+		}
+		if len(tags) >= 4 {
+			return nil, fmt.Errorf("field %s: tag options are not supported", f.Names[0].Name)
+		}
+		index, err := strconv.ParseInt(tags[0], 10, 64)
+		if err != nil {
+			index = -1
+		}
+		var format string
+		if len(tags) >= 3 {
+			format = tags[2]
+		}
+
+		k, bits, err := fieldKind(f.Type, specs, false)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", f.Names[0].Name, err)
+		}
+		for _, n := range f.Names {
+			fields = append(fields, genField{
+				name:   n.Name,
+				index:  int(index),
+				format: format,
+				kind:   k,
+				bits:   bits,
+			})
+		}
+	}
+	return fields, nil
+}
+
+// fieldKind returns kind of the field type. named is true if expr is the
+// underlying type of a defined type, which is not time.Time for csve.
+func fieldKind(expr ast.Expr, specs map[string]*ast.TypeSpec, named bool) (kind, int, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if k, ok := basicKinds[t.Name]; ok {
+			bits := 64
+			if t.Name == "float32" {
+				bits = 32
+			}
+			return k, bits, nil
+		}
+		if ts, ok := specs[t.Name]; ok {
+			return fieldKind(ts.Type, specs, named || !ts.Assign.IsValid())
+		}
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok && x.Name == "time" && t.Sel.Name == "Time" && !named {
+			return kindTime, 0, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("unsupported type %s", exprString(expr))
+}
+
+func exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	format.Node(&buf, token.NewFileSet(), expr)
+	return buf.String()
+}
+
+func writeMethods(w *bytes.Buffer, name string, fields []genField) {
+	fmt.Fprintf(w, "\n// DecodeCSV decodes record into v.\n")
+	fmt.Fprintf(w, "func (v *%s) DecodeCSV(d *csve.Decoder, record []string) error {\n", name)
+	for _, f := range fields {
+		var call string
+		switch f.kind {
+		case kindInt:
+			call = fmt.Sprintf("csve.DecodeInt(d, &v.%s, record, %d, %q)", f.name, f.index, f.name)
+		case kindUint:
+			call = fmt.Sprintf("csve.DecodeUint(d, &v.%s, record, %d, %q)", f.name, f.index, f.name)
+		case kindFloat:
+			call = fmt.Sprintf("csve.DecodeFloat(d, &v.%s, record, %d, %q)", f.name, f.index, f.name)
+		case kindBool:
+			call = fmt.Sprintf("csve.DecodeBool(d, &v.%s, record, %d, %q)", f.name, f.index, f.name)
+		case kindString:
+			call = fmt.Sprintf("csve.DecodeString(d, &v.%s, record, %d, %q)", f.name, f.index, f.name)
+		case kindTime:
+			call = fmt.Sprintf("csve.DecodeTime(d, &v.%s, record, %d, %q, %q)", f.name, f.index, f.format, f.name)
+		}
+		fmt.Fprintf(w, "if err := %s; err != nil {\nreturn err\n}\n", call)
+	}
+	fmt.Fprintf(w, "return nil\n}\n")
+
+	fmt.Fprintf(w, "\n// EncodeCSV encodes v into a record.\n")
+	fmt.Fprintf(w, "func (v *%s) EncodeCSV(e *csve.Encoder) ([]string, error) {\n", name)
+	fmt.Fprintf(w, "return []string{\n")
+	for _, f := range fields {
+		switch f.kind {
+		case kindInt:
+			fmt.Fprintf(w, "strconv.FormatInt(int64(v.%s), 10),\n", f.name)
+		case kindUint:
+			fmt.Fprintf(w, "strconv.FormatUint(uint64(v.%s), 10),\n", f.name)
+		case kindFloat:
+			fmt.Fprintf(w, "strconv.FormatFloat(float64(v.%s), 'f', -1, %d),\n", f.name, f.bits)
+		case kindBool:
+			fmt.Fprintf(w, "strconv.FormatBool(bool(v.%s)),\n", f.name)
+		case kindString:
+			fmt.Fprintf(w, "string(v.%s),\n", f.name)
+		case kindTime:
+			fmt.Fprintf(w, "csve.EncodeTime(e, v.%s, %q),\n", f.name, f.format)
+		}
+	}
+	fmt.Fprintf(w, "}, nil\n}\n")
+}
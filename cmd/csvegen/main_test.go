@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	src := "package p\n\nimport \"time\"\n\n" +
+		"type Code string\n\n" +
+		"type Stamp time.Time\n\n" +
+		"type V struct {\n" +
+		"\tA, B int `csv:\"0,a\"`\n" +
+		"\tC Code `csv:\"1,c\"`\n" +
+		"\tD float32 `csv:\"2,d\"`\n" +
+		"\tT time.Time `csv:\"3,t,2006-01-02\"`\n" +
+		"\tX string\n" +
+		"}\n\n" +
+		"type Opt struct {\n\tA int `csv:\"0,a,,thousands=,\"`\n}\n\n" +
+		"type Ptr struct {\n\tA *int `csv:\"0,a\"`\n}\n\n" +
+		"type Named struct {\n\tA Stamp `csv:\"0,a\"`\n}\n"
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := generate(dir, []string{"V"}, "v_csve.go", false)
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	for _, want := range []string{
+		"package p\n",
+		`csve.DecodeInt(d, &v.A, record, 0, "A")`,
+		`csve.DecodeInt(d, &v.B, record, 0, "B")`,
+		`csve.DecodeString(d, &v.C, record, 1, "C")`,
+		`csve.DecodeTime(d, &v.T, record, 3, "2006-01-02", "T")`,
+		"strconv.FormatFloat(float64(v.D), 'f', -1, 32),",
+		"string(v.C),",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("generate() does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(string(got), "v.X") {
+		t.Errorf("generate() contains untagged field:\n%s", got)
+	}
+
+	for _, name := range []string{"Opt", "Ptr", "Named", "Code", "Missing"} {
+		if _, err := generate(dir, []string{name}, "v_csve.go", false); err == nil {
+			t.Errorf("generate(%s) error = nil, want error", name)
+		}
+	}
+}
+
+func TestGenerate_packages(t *testing.T) {
+	files := map[string]string{
+		"p.go":          "package p\n\ntype V struct {\n\tA int `csv:\"0,a\"`\n}\n",
+		"p_test.go":     "package p\n\ntype T struct {\n\tB int `csv:\"0,b\"`\n}\n",
+		"p_ext_test.go": "package p_test\n\ntype V struct {\n\tC int `csv:\"0,c\"`\n}\n\ntype W struct {\n\tD int `csv:\"0,d\"`\n}\n",
+	}
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		types   []string
+		tests   bool
+		want    []string
+		wantErr bool
+	}{
+		{types: []string{"V"}, want: []string{"package p\n", "&v.A"}},
+		{types: []string{"W"}, wantErr: true},
+		{types: []string{"T"}, wantErr: true},
+		{types: []string{"W"}, tests: true, want: []string{"package p_test\n", "&v.D"}},
+		{types: []string{"T"}, tests: true, want: []string{"package p\n", "&v.B"}},
+		{types: []string{"V"}, tests: true, wantErr: true},
+		{types: []string{"T", "W"}, tests: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := generate(dir, tt.types, "out.go", tt.tests)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("generate(%v, tests=%v) error = %v, wantErr %v", tt.types, tt.tests, err, tt.wantErr)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(got), want) {
+				t.Errorf("generate(%v, tests=%v) does not contain %q:\n%s", tt.types, tt.tests, want, got)
+			}
+		}
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	abs := filepath.Join(t.TempDir(), "gen.go")
+	tests := []struct {
+		output string
+		tests  bool
+		want   string
+	}{
+		{"", false, filepath.Join("pkg", "order_csve.go")},
+		{"", true, filepath.Join("pkg", "order_csve_test.go")},
+		{"gen.go", false, filepath.Join("pkg", "gen.go")},
+		{abs, false, abs},
+	}
+	for _, tt := range tests {
+		if got := outputPath("pkg", tt.output, "Order", tt.tests); got != tt.want {
+			t.Errorf("outputPath(%q, tests=%v) = %q, want %q", tt.output, tt.tests, got, tt.want)
+		}
+	}
+}
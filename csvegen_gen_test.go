@@ -0,0 +1,44 @@
+// Code generated by csvegen; DO NOT EDIT.
+
+package csve_test
+
+import (
+	"strconv"
+
+	"github.com/yuichi1004/csve"
+)
+
+// DecodeCSV decodes record into v.
+func (v *testGenData) DecodeCSV(d *csve.Decoder, record []string) error {
+	if err := csve.DecodeInt(d, &v.ID, record, 0, "ID"); err != nil {
+		return err
+	}
+	if err := csve.DecodeString(d, &v.Name, record, 1, "Name"); err != nil {
+		return err
+	}
+	if err := csve.DecodeFloat(d, &v.Price, record, 2, "Price"); err != nil {
+		return err
+	}
+	if err := csve.DecodeBool(d, &v.Active, record, 3, "Active"); err != nil {
+		return err
+	}
+	if err := csve.DecodeUint(d, &v.Status, record, 4, "Status"); err != nil {
+		return err
+	}
+	if err := csve.DecodeTime(d, &v.Created, record, 5, "2006-01-02T15:04:05", "Created"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// EncodeCSV encodes v into a record.
+func (v *testGenData) EncodeCSV(e *csve.Encoder) ([]string, error) {
+	return []string{
+		strconv.FormatInt(int64(v.ID), 10),
+		string(v.Name),
+		strconv.FormatFloat(float64(v.Price), 'f', -1, 32),
+		strconv.FormatBool(bool(v.Active)),
+		strconv.FormatUint(uint64(v.Status), 10),
+		csve.EncodeTime(e, v.Created, "2006-01-02T15:04:05"),
+	}, nil
+}
@@ -0,0 +1,181 @@
+package csve_test
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/yuichi1004/csve"
+)
+
+//go:generate go run ./cmd/csvegen -type testGenData -tests -output csvegen_gen_test.go
+
+type testGenStatus uint8
+
+type testGenData struct {
+	ID      int64         `csv:"0,id"`
+	Name    string        `csv:"1,name"`
+	Price   float32       `csv:"2,price"`
+	Active  bool          `csv:"3,active"`
+	Status  testGenStatus `csv:"4,status"`
+	Created time.Time     `csv:"5,created,2006-01-02T15:04:05"`
+	Ignored string
+}
+
+// testReflectData is testGenData without generated methods.
+type testReflectData struct {
+	ID      int64         `csv:"0,id"`
+	Name    string        `csv:"1,name"`
+	Price   float32       `csv:"2,price"`
+	Active  bool          `csv:"3,active"`
+	Status  testGenStatus `csv:"4,status"`
+	Created time.Time     `csv:"5,created,2006-01-02T15:04:05"`
+	Ignored string
+}
+
+func TestGenerated(t *testing.T) {
+	var _ csve.CSVDecoder = &testGenData{}
+	var _ csve.CSVEncoder = &testGenData{}
+
+	raw := "1,abc,2.5,true,3,2017-12-24T15:30:00\n"
+	for name, reader := range csve.TestReaders(raw) {
+		t.Run(name, func(t *testing.T) {
+			gd, _ := csve.NewDecoder(reader(), false)
+			var got testGenData
+			if err := gd.Decode(&got); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			rd, _ := csve.NewDecoder(reader(), false)
+			var want testReflectData
+			if err := rd.Decode(&want); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if testReflectData(got) != want {
+				t.Errorf("Decode() = %+v, want %+v", got, want)
+			}
+
+			var b strings.Builder
+			w := csv.NewWriter(&b)
+			e, _ := csve.NewEncoder(w, false)
+			if err := e.Encode(got); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			w.Flush()
+			if b.String() != raw {
+				t.Errorf("Encode() = %q, want %q", b.String(), raw)
+			}
+		})
+	}
+}
+
+func TestGenerated_DecodeInto(t *testing.T) {
+	raw := "1,abc,2.5,true,3,2017-12-24T15:30:00\n2,abc,1.5,false,4,2017-12-25T15:30:00\n"
+	d, _ := csve.NewDecoder(csve.NewTokenizer(strings.NewReader(raw)), false)
+	var v1, v2 testGenData
+	if err := d.DecodeInto(&v1); err != nil {
+		t.Fatalf("DecodeInto() error = %v", err)
+	}
+	if err := d.DecodeInto(&v2); err != nil {
+		t.Fatalf("DecodeInto() error = %v", err)
+	}
+	if v1.Name != "abc" || v2.ID != 2 || unsafe.StringData(v1.Name) != unsafe.StringData(v2.Name) {
+		t.Errorf("DecodeInto() = %+v, %+v, want shared Name", v1, v2)
+	}
+}
+
+func TestGenerated_error(t *testing.T) {
+	raw := "1,abc,2.5,true,256,2017-12-24T15:30:00\n"
+	gd, _ := csve.NewDecoder(csv.NewReader(strings.NewReader(raw)), false)
+	rd, _ := csve.NewDecoder(csv.NewReader(strings.NewReader(raw)), false)
+
+	gerr := gd.Decode(&testGenData{})
+	rerr := rd.Decode(&testReflectData{})
+	if gerr == nil || rerr == nil || gerr.Error() != rerr.Error() {
+		t.Errorf("Decode() error = %v, want %v", gerr, rerr)
+	}
+	if _, ok := reflect.TypeOf(gerr).MethodByName("Cause"); !ok {
+		t.Errorf("Decode() error = %T, want wrapped error", gerr)
+	}
+}
+
+func TestGenerated_checks(t *testing.T) {
+	raw := "1,abc,2.5\n1,abc,2.5\n"
+	d, _ := csve.NewDecoder(csv.NewReader(strings.NewReader(raw)), false)
+	d.StrictColumns = true
+	for i := 0; i < 2; i++ {
+		var ce *csve.ColumnError
+		if err := d.Decode(&testGenData{}); !errors.As(err, &ce) {
+			t.Errorf("Decode() error = %v, want *ColumnError", err)
+		}
+	}
+	if err := d.Decode((*testGenData)(nil)); err == nil {
+		t.Errorf("Decode(nil) error = nil, want error")
+	}
+
+	var b strings.Builder
+	e, _ := csve.NewEncoderFromWriter(&b, csve.Dialect{Header: true})
+	if err := e.Encode(&testGenData{ID: 1}); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if err := e.Encode((*testGenData)(nil)); err == nil {
+		t.Errorf("Encode(nil) error = nil, want error")
+	}
+	e.Flush()
+	want := "id,name,price,active,status,created\n1,,0,false,0,0001-01-01T00:00:00\n"
+	if b.String() != want {
+		t.Errorf("Encode() = %q, want %q", b.String(), want)
+	}
+}
+
+// benchRecord is returned by benchRecordReader for each read, so the
+// benchmarks of generated and reflective decoding measure Decode without
+// the csv parser. created is empty to leave out time parsing, which costs
+// the same in both.
+var benchRecord = []string{"1", "str", "2.0", "true", "3", ""}
+
+type benchRecordReader struct{}
+
+func (benchRecordReader) Read() ([]string, error) {
+	return benchRecord, nil
+}
+
+func BenchmarkDecodeGenerated(b *testing.B) {
+	dec, _ := csve.NewDecoder(benchRecordReader{}, false)
+
+	var d testGenData
+	for i := 0; i < b.N; i++ {
+		dec.Decode(&d)
+	}
+}
+
+func BenchmarkDecodeReflect(b *testing.B) {
+	dec, _ := csve.NewDecoder(benchRecordReader{}, false)
+
+	var d testReflectData
+	for i := 0; i < b.N; i++ {
+		dec.Decode(&d)
+	}
+}
+
+func BenchmarkEncodeGenerated(b *testing.B) {
+	enc, _ := csve.NewEncoder(csv.NewWriter(io.Discard), false)
+
+	d := testGenData{ID: 1, Name: "str", Price: 2, Active: true, Status: 3}
+	for i := 0; i < b.N; i++ {
+		enc.Encode(&d)
+	}
+}
+
+func BenchmarkEncodeReflect(b *testing.B) {
+	enc, _ := csve.NewEncoder(csv.NewWriter(io.Discard), false)
+
+	d := testReflectData{ID: 1, Name: "str", Price: 2, Active: true, Status: 3}
+	for i := 0; i < b.N; i++ {
+		enc.Encode(&d)
+	}
+}
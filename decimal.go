@@ -0,0 +1,207 @@
+package csve
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var decimalType = reflect.TypeOf(Decimal{})
+
+// Decimal is an exact decimal number held as unscaled integer and scale.
+// "12.30" is held as 1230 with scale 2, so it is encoded back to "12.30"
+// without the drift of float64. The zero value is 0.
+//
+// Decimal is immutable. Use scale and round tag options to fix the scale of
+// a column, like `csv:"0,price,,scale=2,round=half_even"`.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+// NewDecimal returns unscaled * 10^-scale.
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	d := Decimal{big.NewInt(unscaled), scale}
+	if scale < 0 {
+		return d.Rescale(0, RoundDown)
+	}
+	return d
+}
+
+// maxDecimalDigits bounds the exponent, scale and number of digits parsed by
+// ParseDecimal, so a cell like "1e50000000" cannot stall decoding.
+const maxDecimalDigits = 10000
+
+// ParseDecimal parses s like "-12.30" or "1.5e3" into Decimal.
+// The scale is the number of digits after the decimal point. The exponent,
+// the scale and the number of digits must not exceed 10000.
+func ParseDecimal(s string) (Decimal, error) {
+	mantissa, exp := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa = s[:i]
+		var err error
+		if exp, err = strconv.Atoi(s[i+1:]); err != nil {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+		}
+	}
+
+	digits := mantissa
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		digits = digits[1:]
+	}
+	intPart, fracPart := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		intPart, fracPart = digits[:i], digits[i+1:]
+	}
+	if intPart+fracPart == "" || strings.IndexFunc(intPart+fracPart, isNotDigit) >= 0 {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	// check before building big.Int, whose size the input decides
+	scale := len(fracPart) - exp
+	digits = intPart + fracPart
+	if exp > maxDecimalDigits || exp < -maxDecimalDigits || scale > maxDecimalDigits ||
+		len(digits)+max(-scale, 0) > maxDecimalDigits {
+		return Decimal{}, fmt.Errorf("decimal %q is out of range", s)
+	}
+
+	unscaled, _ := new(big.Int).SetString(digits, 10)
+	if strings.HasPrefix(mantissa, "-") {
+		unscaled.Neg(unscaled)
+	}
+	if scale < 0 {
+		unscaled.Mul(unscaled, pow10(-scale))
+		scale = 0
+	}
+	return Decimal{unscaled, int32(scale)}, nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics if s cannot be parsed.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func isNotDigit(r rune) bool {
+	return r < '0' || r > '9'
+}
+
+// Unscaled returns unscaled integer of d.
+func (d Decimal) Unscaled() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(d.unscaled)
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of d.
+func (d Decimal) Sign() int {
+	if d.unscaled == nil {
+		return 0
+	}
+	return d.unscaled.Sign()
+}
+
+// Cmp compares d and x numerically, so 1.0 and 1.00 are equal.
+func (d Decimal) Cmp(x Decimal) int {
+	return d.Rat().Cmp(x.Rat())
+}
+
+// Rescale returns d with scale digits after the decimal point.
+// Extra digits are rounded by mode.
+func (d Decimal) Rescale(scale int32, mode RoundingMode) Decimal {
+	n := d.Unscaled()
+	switch {
+	case scale > d.scale:
+		n.Mul(n, pow10(int(scale-d.scale)))
+	case scale < d.scale:
+		n = roundDiv(n, pow10(int(d.scale-scale)), mode)
+	}
+	return Decimal{n, scale}
+}
+
+// Rat returns d as big.Rat.
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.Unscaled(), pow10(int(d.scale)))
+}
+
+// Float64 returns the nearest float64 value of d.
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// String returns d in plain decimal notation, keeping its scale.
+func (d Decimal) String() string {
+	return formatScaled(d.Unscaled(), int(d.scale))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Decimal) UnmarshalText(text []byte) error {
+	v, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func decimalDecoder(opts *tagOptions) fieldDecoder {
+	nf, scale, hasScale := opts.num, opts.scale, opts.hasScale
+	mode := RoundHalfUp
+	if nf != nil {
+		mode = nf.round
+	}
+	return func(d *Decoder, v reflect.Value, raw, format string) error {
+		s, pct, err := normalizeDecimal(raw, nf)
+		if err != nil {
+			return err
+		}
+		dec, err := ParseDecimal(s)
+		if err != nil {
+			return err
+		}
+		if pct {
+			dec.scale += 2
+		}
+		if hasScale {
+			dec = dec.Rescale(scale, mode)
+		}
+		v.Set(reflect.ValueOf(dec))
+		return nil
+	}
+}
+
+func decimalEncoder(opts *tagOptions) fieldEncoder {
+	nf, scale, hasScale := opts.num, opts.scale, opts.hasScale
+	mode := RoundHalfUp
+	if nf != nil {
+		mode = nf.round
+	}
+	return func(e *Encoder, v reflect.Value, format string) (string, error) {
+		dec := v.Interface().(Decimal)
+		if hasScale {
+			dec = dec.Rescale(scale, mode)
+		}
+		s := dec.String()
+		if nf != nil {
+			s = nf.format(s)
+		}
+		return s, nil
+	}
+}
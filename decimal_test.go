@@ -0,0 +1,118 @@
+package csve
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		s         string
+		want      string
+		wantScale int32
+		wantErr   bool
+	}{
+		{s: "12.30", want: "12.30", wantScale: 2},
+		{s: "-0.1", want: "-0.1", wantScale: 1},
+		{s: "+5", want: "5", wantScale: 0},
+		{s: ".5", want: "0.5", wantScale: 1},
+		{s: "1.5e3", want: "1500", wantScale: 0},
+		{s: "1.5e-3", want: "0.0015", wantScale: 4},
+		{s: "123456789012345678901234567890.123456789", want: "123456789012345678901234567890.123456789", wantScale: 9},
+		{s: "", wantErr: true},
+		{s: "1.2.3", wantErr: true},
+		{s: "1e", wantErr: true},
+		{s: "abc", wantErr: true},
+		{s: "1e9999", want: "1" + strings.Repeat("0", 9999), wantScale: 0},
+		{s: "1e50000000", wantErr: true},
+		{s: "1e-50000000", wantErr: true},
+		{s: "1e99999999999999999999", wantErr: true},
+		{s: "0." + strings.Repeat("1", 10001), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseDecimal(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDecimal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.String() != tt.want || got.Scale() != tt.wantScale {
+				t.Errorf("ParseDecimal() = %v (scale %d), want %v (scale %d)", got, got.Scale(), tt.want, tt.wantScale)
+			}
+		})
+	}
+}
+
+func TestDecimal_Rescale(t *testing.T) {
+	tests := []struct {
+		s     string
+		scale int32
+		mode  RoundingMode
+		want  string
+	}{
+		{"12.345", 2, RoundHalfUp, "12.35"},
+		{"12.345", 2, RoundHalfEven, "12.34"},
+		{"-12.345", 2, RoundHalfUp, "-12.35"},
+		{"-12.345", 2, RoundFloor, "-12.35"},
+		{"-12.345", 2, RoundCeiling, "-12.34"},
+		{"12.3", 3, RoundHalfUp, "12.300"},
+		{"0.004", 2, RoundHalfUp, "0.00"},
+	}
+	for _, tt := range tests {
+		if got := MustParseDecimal(tt.s).Rescale(tt.scale, tt.mode).String(); got != tt.want {
+			t.Errorf("Rescale(%v, %d, %d) = %v, want %v", tt.s, tt.scale, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestDecimal_Cmp(t *testing.T) {
+	if MustParseDecimal("1.0").Cmp(MustParseDecimal("1.00")) != 0 {
+		t.Errorf("1.0 and 1.00 should be equal")
+	}
+	if (Decimal{}).Cmp(NewDecimal(1, 2)) >= 0 {
+		t.Errorf("0 should be less than 0.01")
+	}
+	if NewDecimal(15, -2).String() != "1500" {
+		t.Errorf("NewDecimal(15, -2) = %v, want 1500", NewDecimal(15, -2))
+	}
+}
+
+func TestDecimal_RoundTrip(t *testing.T) {
+	type data struct {
+		Raw    Decimal  `csv:"0,raw"`
+		Price  Decimal  `csv:"1,price,,scale=2,round=half_even"`
+		Amount *Decimal `csv:"2,amount,,thousands=,,accounting"`
+	}
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"exact", "0.10,12.30,\"(1,234.50)\"\n", "0.10,12.30,\"(1,234.50)\"\n"},
+		{"rescale", "1.000,0.125,\n", "1.000,0.12,\n"},
+		{"pad", "-7,3,1000000\n", "-7,3.00,\"1,000,000\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(tt.raw)), false)
+			var v data
+			if err := d.Decode(&v); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+
+			buf := new(bytes.Buffer)
+			e, _ := NewEncoder(csv.NewWriter(buf), false)
+			if err := e.Encode(&v); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			e.Flush()
+			if buf.String() != tt.want {
+				t.Errorf("Encode() = %v, want %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package csve
 
 import (
+	"context"
+	"encoding/csv"
+	"io"
 	"reflect"
 	"runtime"
+	"strings"
 	"time"
+	"unsafe"
 
 	"github.com/pkg/errors"
 )
@@ -27,7 +32,68 @@ type Decoder struct {
 	// Custom decoder to customize decoding process.
 	CustomDecoder CustomDecoder
 
-	line int
+	// Cells equal to one of NullValues (like "NULL" or "\\N") are decoded as nil
+	// for pointer fields. An empty cell is always decoded as nil.
+	NullValues []string
+
+	// If EmptyAsZero is true, an empty cell is decoded as the zero value of
+	// the field instead of failing conversion (e.g. ParseInt("")).
+	// Use default tag option to give another value like `csv:"0,qty,,default=1"`.
+	EmptyAsZero bool
+
+	// If StrictColumns is true, Decode returns *ColumnError when a record has
+	// fewer or more columns than the struct expects.
+	StrictColumns bool
+
+	// Normalization is applied to every cell before conversion, in addition
+	// to normalization tag options like trim and nfkc.
+	Normalization Normalization
+
+	// OnProgress, if not nil, is called every ProgressEvery records read by
+	// Decode, not counting the header. Default of ProgressEvery is 1000.
+	OnProgress    func(p Progress)
+	ProgressEvery int
+
+	// InternLimit is the maximum number of distinct strings kept by
+	// DecodeInto. Default is 4096.
+	InternLimit int
+
+	line    int
+	offset  int64 // input offset of the last record, -1 if unknown
+	endLine int   // line of CsvReader where the last record ends
+	records int
+	skipped int
+	headers int // header lines counted in skipped
+
+	// lineBase and offsetBase are added to positions reported by CsvReader,
+	// which reads a part of the input for ChunkDecoder. offsetBase is -1 if
+	// offsets in the input are unknown.
+	lineBase   int
+	offsetBase int64
+
+	// cols holds cells borrowed from CsvBytesReader.
+	cols     []string
+	borrowed bool
+
+	// positions, if not nil, are the positions of cells of the record
+	// decoded by a worker of ParallelDecoder, which has no CsvReader.
+	positions []fieldPos
+
+	interning bool
+	interned  map[string]string
+
+	// genType is the type of the last value decoded by its DecodeCSV
+	// method, and genPlan is its plan.
+	genType reflect.Type
+	genPlan *plan
+}
+
+// Progress is a snapshot of Decoder counters.
+type Progress struct {
+	Line    int
+	Records int
+	Skipped int
+	Bytes   int64
 }
 
 // NewDecoder returns a NewDecoder which decodes values from reader.
@@ -40,12 +106,196 @@ func NewDecoder(reader CsvReader, useHeader bool) (*Decoder, error) {
 	}
 
 	return &Decoder{
-		reader, time.UTC, nil, 0,
+		CsvReader: reader,
+		Location:  time.UTC,
+		offset:    -1,
 	}, nil
 }
 
 // Decode reads csv line and decode values into v.
-func (d *Decoder) Decode(v interface{}) (err error) {
+func (d *Decoder) Decode(v interface{}) error {
+	plan, err := d.getPlan(v)
+	if err != nil {
+		return err
+	}
+
+	cols, err := d.readRecord()
+	if err != nil {
+		return err
+	}
+
+	err = d.decodeRecord(plan, v, cols)
+	if err != nil {
+		d.skipped++
+	} else {
+		d.records++
+	}
+	d.progress()
+	return err
+}
+
+// getPlan validates v and returns its plan. The plan of a CSVDecoder is kept
+// in d, so decoding generated types does not look up the plan cache for each
+// record.
+func (d *Decoder) getPlan(v interface{}) (*plan, error) {
+	if _, ok := v.(CSVDecoder); ok && d.CustomDecoder == nil {
+		if t := reflect.TypeOf(v); t == d.genType {
+			if reflect.ValueOf(v).IsNil() {
+				return nil, errors.New("invalid value type")
+			}
+			return d.genPlan, nil
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, errors.New("invalid value type")
+	}
+	plan, err := getPlan(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.(CSVDecoder); ok {
+		d.genType, d.genPlan = rv.Type(), plan
+	}
+	return plan, nil
+}
+
+// DecodeInto is like Decode, but values of string fields are interned in a
+// table of the Decoder, so repeated values like status codes and country
+// names share one string instead of allocating for each cell. Reusing v
+// between calls with Tokenizer, a record whose strings are all in the table
+// is decoded without allocation.
+//
+// The table keeps up to InternLimit distinct strings for the lifetime of the
+// Decoder. Values of high cardinality columns beyond the limit are copied as
+// Decode does.
+func (d *Decoder) DecodeInto(v interface{}) error {
+	d.interning = true
+	err := d.Decode(v)
+	d.interning = false
+	return err
+}
+
+// internString returns the string equal to s in the intern table, adding a
+// copy of s if the table is not full.
+func (d *Decoder) internString(s string) string {
+	if is, ok := d.interned[s]; ok {
+		return is
+	}
+	limit := d.InternLimit
+	if limit <= 0 {
+		limit = 4096
+	}
+	if len(d.interned) >= limit {
+		if d.borrowed {
+			return strings.Clone(s)
+		}
+		return s
+	}
+
+	// copy s so a table entry does not keep the record of csv.Reader alive
+	s = strings.Clone(s)
+	if d.interned == nil {
+		d.interned = make(map[string]string)
+	}
+	d.interned[s] = s
+	return s
+}
+
+// Line returns the line number of the last record read. It is the physical
+// line where the record begins if CsvReader is a CsvPositionReader, like
+// csv.Reader and Tokenizer, otherwise the number of records read.
+func (d *Decoder) Line() int {
+	return d.line
+}
+
+// Records returns the number of records decoded by Decode.
+func (d *Decoder) Records() int {
+	return d.records
+}
+
+// Skipped returns the number of records read but not decoded, which are the
+// header and records Decode failed to decode.
+func (d *Decoder) Skipped() int {
+	return d.skipped
+}
+
+// Bytes returns the number of bytes consumed by CsvReader, if it reports
+// them by InputOffset like csv.Reader and Tokenizer. Otherwise it returns 0.
+// The count is after transcoding into UTF-8.
+func (d *Decoder) Bytes() int64 {
+	if r, ok := d.CsvReader.(interface{ InputOffset() int64 }); ok {
+		return r.InputOffset()
+	}
+	return 0
+}
+
+func (d *Decoder) progress() {
+	if d.OnProgress == nil {
+		return
+	}
+	every := d.ProgressEvery
+	if every <= 0 {
+		every = 1000
+	}
+	if (d.records+d.skipped-d.headers)%every == 0 {
+		d.OnProgress(Progress{
+			Line:    d.line,
+			Records: d.records,
+			Skipped: d.skipped,
+			Bytes:   d.Bytes(),
+		})
+	}
+}
+
+// DecodeContext is like Decode but returns ctx.Err() without reading if ctx
+// is done.
+func (d *Decoder) DecodeContext(ctx context.Context, v interface{}) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	return d.Decode(v)
+}
+
+// DecodeAllContext decodes all remaining records and appends them to the slice
+// v points to, which is a slice of structs or pointers to structs. ctx is
+// checked between records, so a long import can be cancelled. It returns the
+// number of records decoded, which are appended even if an error occurs.
+func (d *Decoder) DecodeAllContext(ctx context.Context, v interface{}) (n int, err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return 0, errors.New("invalid value type")
+	}
+	slice := rv.Elem()
+	elem := slice.Type().Elem()
+	ptr := elem.Kind() == reflect.Ptr
+	if ptr {
+		elem = elem.Elem()
+	}
+
+	for {
+		p := reflect.New(elem)
+		if err := d.DecodeContext(ctx, p.Interface()); err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+		if ptr {
+			slice.Set(reflect.Append(slice, p))
+		} else {
+			slice.Set(reflect.Append(slice, p.Elem()))
+		}
+		n++
+	}
+}
+
+// decodeRecord decodes cols of the record at d.line into the struct v
+// points to.
+func (d *Decoder) decodeRecord(plan *plan, v interface{}, cols []string) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if _, ok := r.(runtime.Error); ok {
@@ -55,43 +305,261 @@ func (d *Decoder) Decode(v interface{}) (err error) {
 		}
 	}()
 
-	rv := reflect.ValueOf(v)
-	if rv.Kind() != reflect.Ptr || rv.IsNil() {
-		return errors.New("invalid value type")
+	if d.StrictColumns || plan.required {
+		if err := d.checkColumns(plan.fields, cols); err != nil {
+			return err
+		}
 	}
 
-	fields, err := getFields(rv.Type())
-	if err != nil {
+	if cd, ok := v.(CSVDecoder); ok && d.CustomDecoder == nil {
+		if err := cd.DecodeCSV(d, cols); err != nil {
+			return err
+		}
+	} else if err := d.decodeFields(plan, reflect.ValueOf(v), cols); err != nil {
 		return err
 	}
 
-	cols, err := d.Read()
-	if err != nil {
-		return err
+	if h, ok := v.(AfterDecoder); ok {
+		if err := h.AfterDecodeCSV(d.line); err != nil {
+			return errors.Wrapf(err, "after decode hook failed (line:%d)", d.line)
+		}
 	}
-	d.line++
 
-	for _, f := range fields {
-		ref := rv.Elem().FieldByIndex(f.fieldindex)
+	return nil
+}
 
-		var v string
-		if f.csvindex < len(cols) {
-			v = cols[f.csvindex]
+// decodeFields decodes cols into the struct rv points to.
+func (d *Decoder) decodeFields(plan *plan, rv reflect.Value, cols []string) (err error) {
+	fields := plan.fields
+	base := rv.UnsafePointer()
+	for i := range fields {
+		f := &fields[i]
+
+		v, _ := d.cell(f, cols)
+		if v == "" && f.csvopts.hasDefault {
+			v = f.csvopts.def
+		}
+		if d.interning && f.interns {
+			v = d.internString(v)
+		} else if d.borrowed && (f.retains || d.CustomDecoder != nil) {
+			v = strings.Clone(v)
 		}
 
+		if op := &plan.ops[i]; op.dec != nil && d.CustomDecoder == nil && !(v == "" && d.EmptyAsZero) {
+			if err := op.dec(d, unsafe.Add(base, op.offset), v, f.csvformat); err != nil {
+				return d.fieldError(err, f.fieldname, v, f.csvindex, cols)
+			}
+			continue
+		}
+
+		ref := rv.Elem().FieldByIndex(f.fieldindex)
 		var ok bool
 		if d.CustomDecoder != nil {
 			ok, err = d.CustomDecoder(d, ref, v, f.csvformat)
 			if err != nil {
-				return errors.Errorf("field %s parse failed (line:%d)", f.fieldname, d.line)
+				return d.fieldError(err, f.fieldname, v, f.csvindex, cols)
 			}
 		}
 		if !ok {
-			if err := f.dec(d, ref, v, f.csvformat); err != nil {
-				return errors.Errorf("field %s parse failed (line:%d)", f.fieldname, d.line)
+			if v == "" && d.EmptyAsZero {
+				ref.Set(reflect.Zero(ref.Type()))
+			} else if err := f.dec(d, ref, v, f.csvformat); err != nil {
+				return d.fieldError(err, f.fieldname, v, f.csvindex, cols)
+			}
+		}
+
+		if f.csvopts.valid != nil {
+			if rule := f.csvopts.valid.check(ref); rule != "" {
+				line, column, offset := d.cellPos(f.csvindex, cols)
+				return &ValidationError{
+					Line:   line,
+					Column: column,
+					Offset: offset,
+					Field:  f.fieldname,
+					Raw:    strings.Clone(v),
+					Rule:   rule,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fieldError returns *DecodeError of the cell at index of cols.
+func (d *Decoder) fieldError(err error, name, raw string, index int, cols []string) error {
+	var rerr *RangeError
+	if errors.As(err, &rerr) {
+		rerr.Field = name
+		rerr.Raw = strings.Clone(raw)
+	}
+	line, column, offset := d.cellPos(index, cols)
+	return &DecodeError{Line: line, Column: column, Offset: offset, Field: name, Err: err}
+}
+
+// cellPos returns the line, column and input offset of the cell at index of
+// the last record read. column is 0 if unknown, and offset is that of the
+// record if the cell is not on the first line of the record.
+func (d *Decoder) cellPos(index int, cols []string) (line, column int, offset int64) {
+	if d.positions != nil && index >= 0 && index < len(d.positions) {
+		p := d.positions[index]
+		return p.line, p.column, p.offset
+	}
+	r, ok := d.CsvReader.(CsvPositionReader)
+	if !ok || index < 0 || index >= len(cols) {
+		return d.line, 0, d.offset
+	}
+	line, column = r.FieldPos(index)
+	line += d.lineBase
+	switch or, ok := r.(offsetReader); {
+	case d.offset < 0:
+		return line, column, -1
+	case ok:
+		return line, column, d.offsetBase + or.fieldOffset(index)
+	case line == d.line:
+		return line, column, d.offset + int64(column-1)
+	}
+	return line, column, d.offset
+}
+
+// readRecord reads a record and updates the line and offset of the record.
+// If CsvReader implements CsvBytesReader, cells are borrowed from its buffer
+// without copy and valid until the next read.
+func (d *Decoder) readRecord() ([]string, error) {
+	pr, positioned := d.CsvReader.(CsvPositionReader)
+	start := int64(-1)
+	if positioned && d.offsetBase >= 0 {
+		start = d.offsetBase + pr.InputOffset()
+	}
+
+	var cols []string
+	var err error
+	br, ok := d.CsvReader.(CsvBytesReader)
+	d.borrowed = ok
+	if ok {
+		var record [][]byte
+		record, err = br.ReadBytes()
+		if err == nil {
+			d.cols = d.cols[:0]
+			for _, b := range record {
+				d.cols = append(d.cols, unsafe.String(unsafe.SliceData(b), len(b)))
+			}
+			cols = d.cols
+		}
+	} else {
+		cols, err = d.Read()
+	}
+
+	if err == io.EOF {
+		return nil, err
+	}
+	if perr, ok := err.(*csv.ParseError); ok {
+		d.line = d.lineBase + perr.StartLine
+	} else if err == nil && positioned && len(cols) > 0 {
+		line, _ := pr.FieldPos(0)
+		d.line = d.lineBase + line
+	} else {
+		d.line++
+	}
+	d.offset = d.recordOffset(start, cols, err)
+	return cols, err
+}
+
+// offsetReader is implemented by Tokenizer, which knows input offsets of
+// records and fields.
+type offsetReader interface {
+	recordOffset() int64
+	fieldOffset(field int) int64
+}
+
+// recordOffset returns the input offset of the record just read, which
+// began reading at start. It is -1 if unknown.
+func (d *Decoder) recordOffset(start int64, cols []string, err error) int64 {
+	pr, ok := d.CsvReader.(CsvPositionReader)
+	if !ok || d.offsetBase < 0 {
+		return -1
+	}
+	if or, ok := pr.(offsetReader); ok {
+		return d.offsetBase + or.recordOffset()
+	}
+
+	// The record begins at start unless comments or empty lines were
+	// skipped, whose lengths are unknown.
+	prev := d.endLine
+	d.endLine = -1
+	perr, _ := err.(*csv.ParseError)
+	if perr != nil && perr.Err != csv.ErrFieldCount {
+		d.endLine = perr.Line
+		return -1
+	}
+	if (err != nil && perr == nil) || len(cols) == 0 {
+		return -1
+	}
+	line, _ := pr.FieldPos(0)
+	last, _ := pr.FieldPos(len(cols) - 1)
+	d.endLine = last + strings.Count(cols[len(cols)-1], "\n")
+	if line != prev+1 {
+		return -1
+	}
+	return start
+}
+
+// cell returns normalized cell of the field. ok is false if the record does
+// not have the column.
+func (d *Decoder) cell(f *field, cols []string) (v string, ok bool) {
+	if f.csvindex >= len(cols) {
+		return "", false
+	}
+	return (d.Normalization | f.csvopts.norm).apply(cols[f.csvindex]), true
+}
+
+// checkColumns checks required cells and the number of columns.
+func (d *Decoder) checkColumns(fields []field, cols []string) error {
+	want := 0
+	var missing []string
+	for i := range fields {
+		f := &fields[i]
+		if f.csvindex+1 > want {
+			want = f.csvindex + 1
+		}
+		if f.csvindex >= len(cols) {
+			if d.StrictColumns || f.csvopts.required {
+				missing = append(missing, f.csvname)
 			}
+		} else if f.csvopts.required {
+			if v, _ := d.cell(f, cols); v == "" {
+				missing = append(missing, f.csvname)
+			}
+		}
+	}
+
+	if !d.StrictColumns {
+		want = 0
+	}
+	if len(missing) > 0 || (want > 0 && len(cols) != want) {
+		return &ColumnError{Line: d.line, Got: len(cols), Want: want, Missing: missing}
+	}
+	return nil
+}
+
+// isNull reports whether raw represents null value of the field.
+func (d *Decoder) isNull(raw string, opts *tagOptions) bool {
+	if raw == "" || (opts.hasNull && raw == opts.null) {
+		return true
+	}
+	for _, n := range d.NullValues {
+		if raw == n {
+			return true
 		}
 	}
+	return false
+}
 
+// skipHeader reads the header line.
+func (d *Decoder) skipHeader() error {
+	if _, err := d.readRecord(); err != nil {
+		return err
+	}
+	d.skipped++
+	d.headers++
 	return nil
 }
@@ -1,12 +1,17 @@
 package csve
 
 import (
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
+	"unsafe"
 )
 
 type TestData struct {
@@ -264,3 +269,446 @@ func ExampleCustomDecoder() {
 
 	// Output: ID:5, Name:Yuichi, Created:0001-01-01 00:00:00 +0000 UTC
 }
+
+func TestDecoder_Decode_overflow(t *testing.T) {
+	type data struct {
+		Int8    int8    `csv:"0,int8"`
+		Uint8   uint8   `csv:"1,uint8"`
+		Float32 float32 `csv:"2,float32"`
+	}
+	type clampData struct {
+		Int8    int8    `csv:"0,int8,,clamp"`
+		Uint8   uint8   `csv:"1,uint8,,clamp"`
+		Float32 float32 `csv:"2,float32,,clamp"`
+	}
+	tests := []struct {
+		name      string
+		raw       string
+		v         interface{}
+		want      interface{}
+		wantField string
+	}{
+		{"int overflow", "300,0,0", &data{}, nil, "Int8"},
+		{"int64 overflow", "99999999999999999999,0,0", &data{}, nil, "Int8"},
+		{"uint overflow", "0,256,0", &data{}, nil, "Uint8"},
+		{"uint negative", "0,-1,0", &data{}, nil, "Uint8"},
+		{"float overflow", "0,0,1e39", &data{}, nil, "Float32"},
+		{"clamp max", "300,256,1e39", &clampData{}, &clampData{127, 255, math.MaxFloat32}, ""},
+		{"clamp min", "-300,-1,-1e400", &clampData{}, &clampData{-128, 0, -math.MaxFloat32}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(tt.raw)), false)
+			err := d.Decode(tt.v)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("Decoder.Decode() error = %v", err)
+				}
+				if !reflect.DeepEqual(tt.v, tt.want) {
+					t.Errorf("Decode() = %v, want %v", tt.v, tt.want)
+				}
+				return
+			}
+			var rerr *RangeError
+			if !errors.As(err, &rerr) {
+				t.Fatalf("Decoder.Decode() error = %v, want RangeError", err)
+			}
+			if rerr.Field != tt.wantField {
+				t.Errorf("RangeError.Field = %v, want %v", rerr.Field, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_null(t *testing.T) {
+	type data struct {
+		Int   *int     `csv:"0,int"`
+		Str   *string  `csv:"1,str"`
+		Float *float64 `csv:"2,float,,null=\\N"`
+	}
+	tests := []struct {
+		name       string
+		raw        string
+		nullValues []string
+		wantNil    []bool
+		wantErr    bool
+	}{
+		{"empty", ",,", nil, []bool{true, true, true}, false},
+		{"decoder null values", "NULL,N/A,1", []string{"NULL", "N/A"}, []bool{true, true, false}, false},
+		{"field null value", `1,a,\N`, nil, []bool{false, false, true}, false},
+		{"unknown null value", "NULL,,", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(tt.raw)), false)
+			d.NullValues = tt.nullValues
+			var v data
+			if err := d.Decode(&v); (err != nil) != tt.wantErr {
+				t.Fatalf("Decoder.Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			gotNil := []bool{v.Int == nil, v.Str == nil, v.Float == nil}
+			if !reflect.DeepEqual(gotNil, tt.wantNil) {
+				t.Errorf("Decode() nil fields = %v, want %v", gotNil, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_default(t *testing.T) {
+	type data struct {
+		Qty   int     `csv:"0,qty,,default=1"`
+		Price float64 `csv:"1,price"`
+		Name  string  `csv:"2,name,,default=unknown"`
+		Ptr   *int    `csv:"3,ptr,,default=0"`
+	}
+	tests := []struct {
+		name        string
+		raw         string
+		emptyAsZero bool
+		want        *data
+		wantErr     bool
+	}{
+		{"default", ",1.5,,", false, &data{1, 1.5, "unknown", new(int)}, false},
+		{"value", "2,1.5,foo,3", false, &data{2, 1.5, "foo", intPtr(3)}, false},
+		{"empty without default", "2,,foo,3", false, nil, true},
+		{"empty as zero", ",,,", true, &data{1, 0, "unknown", new(int)}, false},
+		{"missing columns", `""`, true, &data{1, 0, "unknown", new(int)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(tt.raw+"\n")), false)
+			d.EmptyAsZero = tt.emptyAsZero
+			var v data
+			if err := d.Decode(&v); (err != nil) != tt.wantErr {
+				t.Fatalf("Decoder.Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(&v, tt.want) {
+				t.Errorf("Decode() = %+v, want %+v", v, tt.want)
+			}
+		})
+	}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestDecoder_Decode_columns(t *testing.T) {
+	type data struct {
+		ID   int    `csv:"0,id,,required"`
+		Name string `csv:"1,name,,required"`
+		Note string `csv:"2,note"`
+	}
+	tests := []struct {
+		name          string
+		raw           string
+		strictColumns bool
+		want          *ColumnError
+	}{
+		{"valid", "1,foo,bar", false, nil},
+		{"optional column missing", "1,foo", false, nil},
+		{"required cell empty", "1,,bar", false, &ColumnError{Line: 1, Got: 3, Missing: []string{"name"}}},
+		{"required column missing", "1", false, &ColumnError{Line: 1, Got: 1, Missing: []string{"name"}}},
+		{"strict fewer columns", "1,foo", true, &ColumnError{Line: 1, Got: 2, Want: 3, Missing: []string{"note"}}},
+		{"strict more columns", "1,foo,bar,baz", true, &ColumnError{Line: 1, Got: 4, Want: 3}},
+		{"strict valid", "1,foo,", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := csv.NewReader(strings.NewReader(tt.raw))
+			r.FieldsPerRecord = -1
+			d, _ := NewDecoder(r, false)
+			d.StrictColumns = tt.strictColumns
+			var v data
+			err := d.Decode(&v)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Decoder.Decode() error = %v", err)
+				}
+				return
+			}
+			var cerr *ColumnError
+			if !errors.As(err, &cerr) {
+				t.Fatalf("Decoder.Decode() error = %v, want ColumnError", err)
+			}
+			if !reflect.DeepEqual(cerr, tt.want) {
+				t.Errorf("Decoder.Decode() error = %#v, want %#v", cerr, tt.want)
+			}
+		})
+	}
+}
+
+type testOrder struct {
+	Qty   int     `csv:"0,qty"`
+	Price float64 `csv:"1,price"`
+	Total float64 `csv:"2,total"`
+
+	line int
+}
+
+func (o *testOrder) AfterDecodeCSV(line int) error {
+	o.line = line
+	if o.Total != float64(o.Qty)*o.Price {
+		return errors.New("total mismatch")
+	}
+	return nil
+}
+
+func (o *testOrder) BeforeEncodeCSV() error {
+	if o.Qty < 0 {
+		return errors.New("negative qty")
+	}
+	o.Total = float64(o.Qty) * o.Price
+	return nil
+}
+
+func TestDecoder_Decode_hook(t *testing.T) {
+	d, _ := NewDecoder(csv.NewReader(strings.NewReader("2,1.5,3\n2,1.5,4\n")), false)
+
+	var v testOrder
+	if err := d.Decode(&v); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if v.line != 1 {
+		t.Errorf("AfterDecodeCSV() line = %v, want 1", v.line)
+	}
+	if err := d.Decode(&v); err == nil || !strings.Contains(err.Error(), "line:2") {
+		t.Errorf("Decoder.Decode() error = %v, want total mismatch at line 2", err)
+	}
+}
+
+func TestDecoder_DecodeAllContext(t *testing.T) {
+	raw := "1,a\n2,b\n3,c\n"
+
+	t.Run("values", func(t *testing.T) {
+		d, _ := NewDecoder(csv.NewReader(strings.NewReader(raw)), false)
+		var got []testRecord
+		n, err := d.DecodeAllContext(context.Background(), &got)
+		if err != nil || n != 3 {
+			t.Fatalf("DecodeAllContext() = %v, %v, want 3, nil", n, err)
+		}
+		want := []testRecord{{1, "a"}, {2, "b"}, {3, "c"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("DecodeAllContext() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("pointers", func(t *testing.T) {
+		d, _ := NewDecoder(csv.NewReader(strings.NewReader(raw)), false)
+		var got []*testRecord
+		if n, err := d.DecodeAllContext(context.Background(), &got); err != nil || n != 3 || got[2].Name != "c" {
+			t.Errorf("DecodeAllContext() = %v, %v", n, err)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		d, _ := NewDecoder(csv.NewReader(strings.NewReader(raw)), false)
+		d.CsvReader = &cancelReader{d.CsvReader, 2, cancel}
+		var got []testRecord
+		n, err := d.DecodeAllContext(ctx, &got)
+		if err != context.Canceled || n != 2 || len(got) != 2 {
+			t.Errorf("DecodeAllContext() = %v, %v, want 2, %v", n, err, context.Canceled)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		d, _ := NewDecoder(csv.NewReader(strings.NewReader("1,a\nx,b\n")), false)
+		var got []testRecord
+		if n, err := d.DecodeAllContext(context.Background(), &got); err == nil || n != 1 {
+			t.Errorf("DecodeAllContext() = %v, %v, want 1, error", n, err)
+		}
+	})
+}
+
+// cancelReader cancels the context after reading n records.
+type cancelReader struct {
+	CsvReader
+	n      int
+	cancel func()
+}
+
+func (r *cancelReader) Read() ([]string, error) {
+	r.n--
+	if r.n == 0 {
+		defer r.cancel()
+	}
+	return r.CsvReader.Read()
+}
+
+func TestDecoder_counters(t *testing.T) {
+	raw := "id,name\n1,a\nx,b\n3,c\n"
+	for name, reader := range testReaders(raw) {
+		t.Run(name, func(t *testing.T) {
+			d, _ := NewDecoder(reader(), false)
+			d.skipHeader()
+			var progress []Progress
+			d.OnProgress = func(p Progress) { progress = append(progress, p) }
+			d.ProgressEvery = 2
+
+			for {
+				if err := d.Decode(&testRecord{}); err == io.EOF {
+					break
+				}
+			}
+			if d.Line() != 4 || d.Records() != 2 || d.Skipped() != 2 || d.Bytes() != int64(len(raw)) {
+				t.Errorf("Line() = %d, Records() = %d, Skipped() = %d, Bytes() = %d",
+					d.Line(), d.Records(), d.Skipped(), d.Bytes())
+			}
+			want := []Progress{
+				{Line: 3, Records: 1, Skipped: 2, Bytes: 16},
+			}
+			if !reflect.DeepEqual(progress, want) {
+				t.Errorf("OnProgress() called with %+v, want %+v", progress, want)
+			}
+		})
+	}
+}
+
+type testPositionData struct {
+	Name string `csv:"0,name"`
+	Qty  int    `csv:"1,qty"`
+}
+
+func TestDecoder_position(t *testing.T) {
+	raw := "name,qty\n\"multi\nline\",1\n\nfoo,x\n\"bar\nbaz\",y\n"
+	wants := map[string][]DecodeError{
+		// csv.Reader does not tell lengths of skipped lines and lines of a
+		// record but the last
+		"csv.Reader": {
+			{Line: 5, Column: 5, Offset: -1, Field: "Qty"},
+			{Line: 7, Column: 6, Offset: 31, Field: "Qty"},
+		},
+		"Tokenizer": {
+			{Line: 5, Column: 5, Offset: 29, Field: "Qty"},
+			{Line: 7, Column: 6, Offset: 41, Field: "Qty"},
+		},
+	}
+	for name, reader := range testReaders(raw) {
+		t.Run(name, func(t *testing.T) {
+			d, _ := NewDecoder(reader(), false)
+			d.skipHeader()
+
+			var v testPositionData
+			if err := d.Decode(&v); err != nil || d.Line() != 2 {
+				t.Fatalf("Decode() error = %v, Line() = %d, want line 2", err, d.Line())
+			}
+			for _, want := range wants[name] {
+				err := d.Decode(&v)
+				var derr *DecodeError
+				if !errors.As(err, &derr) {
+					t.Fatalf("Decode() error = %v, want DecodeError", err)
+				}
+				derr.Err = nil
+				if *derr != want {
+					t.Errorf("Decode() error = %+v, want %+v", *derr, want)
+				}
+			}
+		})
+	}
+
+	// comment lines before the record starting at 14
+	tok := NewTokenizer(strings.NewReader("a,1\n# c1\n# c2\nx,b\n"))
+	tok.Comment = '#'
+	d, _ := NewDecoder(tok, false)
+	d.Decode(&testPositionData{})
+	var derr *DecodeError
+	if err := d.Decode(&testPositionData{}); !errors.As(err, &derr) || derr.Line != 4 || derr.Offset != 16 {
+		t.Errorf("Decode() error = %#v, want DecodeError at line 4 and offset 16", err)
+	}
+	d, _ = NewDecoder(csv.NewReader(strings.NewReader("a,x\nb,y\n")), false)
+	for _, want := range []int64{2, 6} {
+		if err := d.Decode(&testPositionData{}); !errors.As(err, &derr) || derr.Offset != want {
+			t.Errorf("Decode() error = %#v, want DecodeError at offset %d", err, want)
+		}
+	}
+
+	// a parse error reports the line of the reader
+	d, _ = NewDecoder(NewTokenizer(strings.NewReader("1,a\n\n\n2,\"b\n")), false)
+	d.Decode(&testPositionData{})
+	if err := d.Decode(&testPositionData{}); err == nil || d.Line() != 4 || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("Decode() error = %v, Line() = %d, want line 4", err, d.Line())
+	}
+
+	// a reader without positions
+	d, _ = NewDecoder(&limitReader{csv.NewReader(strings.NewReader("foo,x\n")), 1}, false)
+	err := d.Decode(&testPositionData{})
+	if !errors.As(err, &derr) || derr.Line != 1 || derr.Column != 0 || derr.Offset != -1 {
+		t.Errorf("Decode() error = %#v, want DecodeError at line 1 without column and offset", err)
+	}
+	if !strings.Contains(err.Error(), "(line:1)") {
+		t.Errorf("Decode() error = %v, want line without column", err)
+	}
+}
+
+type testInternData struct {
+	Status  string  `csv:"0,status"`
+	Country *string `csv:"1,country"`
+	Qty     int     `csv:"2,qty"`
+}
+
+func TestDecoder_DecodeInto(t *testing.T) {
+	raw := "shipped,JP,1\npending,US,2\nshipped,JP,3\nshipped,US,4\n"
+	for name, reader := range testReaders(raw) {
+		t.Run(name, func(t *testing.T) {
+			d, _ := NewDecoder(reader(), false)
+			var got []testInternData
+			for {
+				var v testInternData
+				if err := d.DecodeInto(&v); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("DecodeInto() error = %v", err)
+				}
+				got = append(got, v)
+			}
+
+			if len(got) != 4 || got[0].Status != "shipped" || *got[1].Country != "US" || got[3].Qty != 4 {
+				t.Fatalf("DecodeInto() = %+v", got)
+			}
+			if unsafe.StringData(got[0].Status) != unsafe.StringData(got[2].Status) ||
+				unsafe.StringData(*got[1].Country) != unsafe.StringData(*got[3].Country) {
+				t.Errorf("DecodeInto() does not share repeated strings")
+			}
+			if len(d.interned) != 4 {
+				t.Errorf("intern table has %d strings, want 4", len(d.interned))
+			}
+		})
+	}
+
+	t.Run("limit", func(t *testing.T) {
+		d, _ := NewDecoder(NewTokenizer(strings.NewReader(raw)), false)
+		d.InternLimit = 1
+		var got []testInternData
+		for {
+			var v testInternData
+			if err := d.DecodeInto(&v); err != nil {
+				break
+			}
+			got = append(got, v)
+		}
+		// strings beyond the limit are copied from the reused buffer
+		if len(d.interned) != 1 || len(got) != 4 || got[1].Status != "pending" || *got[0].Country != "JP" {
+			t.Errorf("DecodeInto() = %+v with %d interned strings", got, len(d.interned))
+		}
+	})
+}
+
+func TestDecoder_DecodeInto_allocs(t *testing.T) {
+	type data struct {
+		Status string `csv:"0,status"`
+		Qty    int    `csv:"1,qty"`
+	}
+	d, _ := NewDecoder(NewTokenizer(&benchCsvSrcReader{[]byte("shipped,1\n")}), false)
+	var v data
+	d.DecodeInto(&v)
+	if n := testing.AllocsPerRun(100, func() { d.DecodeInto(&v) }); n != 0 {
+		t.Errorf("DecodeInto() allocates %v times per record, want 0", n)
+	}
+	if v.Status != "shipped" || v.Qty != 1 {
+		t.Errorf("DecodeInto() = %+v", v)
+	}
+}
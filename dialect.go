@@ -0,0 +1,268 @@
+package csve
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Charset is a character encoding of csv bytes.
+type Charset int
+
+const (
+	// UTF8 is the default charset.
+	UTF8 Charset = iota
+	// ShiftJIS is Shift_JIS, used by Excel in Japanese locale.
+	ShiftJIS
+	// EUCJP is EUC-JP.
+	EUCJP
+	// UTF16LE is UTF-16 little endian.
+	UTF16LE
+	// UTF16BE is UTF-16 big endian.
+	UTF16BE
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+func (c Charset) encoding() encoding.Encoding {
+	switch c {
+	case ShiftJIS:
+		return japanese.ShiftJIS
+	case EUCJP:
+		return japanese.EUCJP
+	case UTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case UTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	}
+	return nil
+}
+
+func (c Charset) bom() []byte {
+	switch c {
+	case UTF8:
+		return utf8BOM
+	case UTF16LE:
+		return utf16LEBOM
+	case UTF16BE:
+		return utf16BEBOM
+	}
+	return nil
+}
+
+// Dialect describes how csv bytes are written. Define it once and share it
+// between decoders and encoders to get consistent CSV/TSV settings.
+// The zero value is RFC 4180 csv in UTF-8.
+type Dialect struct {
+	// Comma is the field delimiter. Default is ','. Use '\t' for TSV.
+	Comma rune
+
+	// Quote is the quote character. Zero means '"'. Another quote must be
+	// ASCII, and so must be Comma and Comment with it, since csv is then
+	// read by Tokenizer instead of encoding/csv.
+	Quote rune
+
+	// Comment, if not 0, is the comment character. Lines beginning with it
+	// are ignored on decode.
+	Comment rune
+
+	// LazyQuotes allows a quote to appear in an unquoted field and a
+	// non-doubled quote to appear in a quoted field.
+	LazyQuotes bool
+
+	// TrimLeadingSpace ignores leading white space in a field.
+	TrimLeadingSpace bool
+
+	// UseCRLF uses \r\n as the line terminator on encode.
+	UseCRLF bool
+
+	// FieldsPerRecord is the number of expected fields per record like
+	// encoding/csv.Reader. Zero means the number of the first record, and
+	// negative means variable number of fields.
+	FieldsPerRecord int
+
+	// If Header is true, the first record is a header line. Decoder skips it
+	// and Encoder writes csv names of the struct before the first record.
+	Header bool
+
+	// Charset of csv bytes. On decode, a byte order mark overrides Charset,
+	// so a UTF-8 BOM is stripped and a UTF-16 BOM selects UTF-16.
+	Charset Charset
+
+	// If BOM is true, Encoder writes byte order mark of Charset first.
+	// Excel requires it to open UTF-8 csv correctly. Ignored for Shift_JIS
+	// and EUC-JP.
+	BOM bool
+}
+
+func (dl Dialect) validate() error {
+	if !dl.customQuote() {
+		return nil
+	}
+	if dl.Quote >= utf8.RuneSelf || dl.Comma >= utf8.RuneSelf || dl.Comment >= utf8.RuneSelf {
+		return errors.New("quote, delimiter and comment must be ASCII with a quote other than '\"'")
+	}
+	if dl.Quote == dl.comma() || dl.Quote == dl.Comment || dl.Quote == '\r' || dl.Quote == '\n' {
+		return fmt.Errorf("invalid quote %q", dl.Quote)
+	}
+	return nil
+}
+
+// customQuote reports whether Quote is other than '"', which encoding/csv
+// does not support.
+func (dl Dialect) customQuote() bool {
+	return dl.Quote != 0 && dl.Quote != '"'
+}
+
+func (dl Dialect) comma() rune {
+	if dl.Comma == 0 {
+		return ','
+	}
+	return dl.Comma
+}
+
+func (dl Dialect) quote() byte {
+	if dl.Quote == 0 {
+		return '"'
+	}
+	return byte(dl.Quote)
+}
+
+// newCsvReader returns csv.Reader, or Tokenizer for a custom quote.
+func (dl Dialect) newCsvReader(r io.Reader) CsvReader {
+	if dl.customQuote() {
+		tk := NewTokenizer(r)
+		tk.Comma = byte(dl.comma())
+		tk.Quote = dl.quote()
+		tk.Comment = byte(dl.Comment)
+		tk.LazyQuotes = dl.LazyQuotes
+		tk.TrimLeadingSpace = dl.TrimLeadingSpace
+		tk.FieldsPerRecord = dl.FieldsPerRecord
+		return tk
+	}
+
+	cr := csv.NewReader(r)
+	cr.Comma = dl.comma()
+	cr.Comment = dl.Comment
+	cr.LazyQuotes = dl.LazyQuotes
+	cr.TrimLeadingSpace = dl.TrimLeadingSpace
+	cr.FieldsPerRecord = dl.FieldsPerRecord
+	return cr
+}
+
+// newCsvWriter returns csv.Writer, or quoteWriter for a custom quote.
+func (dl Dialect) newCsvWriter(w io.Writer) CsvWriter {
+	if dl.customQuote() {
+		return &quoteWriter{
+			Comma:   byte(dl.comma()),
+			Quote:   dl.quote(),
+			UseCRLF: dl.UseCRLF,
+			w:       bufio.NewWriter(w),
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = dl.comma()
+	cw.UseCRLF = dl.UseCRLF
+	return cw
+}
+
+// NewDecoderFromReader returns a Decoder which reads csv bytes from r.
+// It strips a byte order mark and transcodes r into UTF-8 based on dialect.
+// If dialect has Header, the header line is read here and counted in the
+// line number of the Decoder.
+func NewDecoderFromReader(r io.Reader, dialect Dialect) (*Decoder, error) {
+	if err := dialect.validate(); err != nil {
+		return nil, err
+	}
+	r, err := newTextReader(r, dialect.Charset)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := NewDecoder(dialect.newCsvReader(r), false)
+	if err != nil {
+		return nil, err
+	}
+	if dialect.Header {
+		if err := d.skipHeader(); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// NewEncoderFromWriter returns a Encoder which writes csv bytes into w.
+// It writes a byte order mark and transcodes UTF-8 into the charset of dialect.
+// Close the Encoder to write trailing bytes of the charset.
+func NewEncoderFromWriter(w io.Writer, dialect Dialect) (*Encoder, error) {
+	if err := dialect.validate(); err != nil {
+		return nil, err
+	}
+	w, err := newTextWriter(w, dialect.Charset, dialect.BOM)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := NewEncoder(dialect.newCsvWriter(w), false)
+	if err != nil {
+		return nil, err
+	}
+	e.header = dialect.Header
+	if tw, ok := w.(*transform.Writer); ok {
+		e.closer = tw
+	}
+	return e, nil
+}
+
+// newTextReader returns a reader which converts r into UTF-8.
+func newTextReader(r io.Reader, charset Charset) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(3)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		br.Discard(len(utf8BOM))
+		charset = UTF8
+	case bytes.HasPrefix(head, utf16LEBOM):
+		br.Discard(len(utf16LEBOM))
+		charset = UTF16LE
+	case bytes.HasPrefix(head, utf16BEBOM):
+		br.Discard(len(utf16BEBOM))
+		charset = UTF16BE
+	}
+
+	if enc := charset.encoding(); enc != nil {
+		return transform.NewReader(br, enc.NewDecoder()), nil
+	}
+	return br, nil
+}
+
+// newTextWriter returns a writer which converts UTF-8 into charset.
+func newTextWriter(w io.Writer, charset Charset, bom bool) (io.Writer, error) {
+	if b := charset.bom(); bom && b != nil {
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+	}
+	if enc := charset.encoding(); enc != nil {
+		return transform.NewWriter(w, enc.NewEncoder()), nil
+	}
+	return w, nil
+}
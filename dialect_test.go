@@ -0,0 +1,192 @@
+package csve
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+type testCharsetData struct {
+	ID   int    `csv:"0,id"`
+	Name string `csv:"1,name"`
+}
+
+func mustEncode(t *testing.T, enc interface {
+	String(string) (string, error)
+}, s string) string {
+	e, err := enc.String(s)
+	if err != nil {
+		t.Fatalf("failed to encode test data: %v", err)
+	}
+	return e
+}
+
+func TestNewDecoderFromReader(t *testing.T) {
+	const text = "1,鈴木ｲﾁﾛｳ\n"
+	tests := []struct {
+		name    string
+		raw     string
+		dialect Dialect
+	}{
+		{"utf-8", text, Dialect{}},
+		{"utf-8 bom", "\xEF\xBB\xBF" + text, Dialect{}},
+		{"utf-8 bom overrides charset", "\xEF\xBB\xBF" + text, Dialect{Charset: ShiftJIS}},
+		{"shift_jis", mustEncode(t, japanese.ShiftJIS.NewEncoder(), text), Dialect{Charset: ShiftJIS}},
+		{"euc-jp", mustEncode(t, japanese.EUCJP.NewEncoder(), text), Dialect{Charset: EUCJP}},
+		{"utf-16le bom", mustEncode(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder(), text), Dialect{}},
+		{"utf-16be", mustEncode(t, unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder(), text), Dialect{Charset: UTF16BE}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDecoderFromReader(strings.NewReader(tt.raw), tt.dialect)
+			if err != nil {
+				t.Fatalf("NewDecoderFromReader() error = %v", err)
+			}
+			var v testCharsetData
+			if err := d.Decode(&v); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if v.ID != 1 || v.Name != "鈴木ｲﾁﾛｳ" {
+				t.Errorf("Decode() = %v, want {1 鈴木ｲﾁﾛｳ}", v)
+			}
+		})
+	}
+}
+
+func TestNewEncoderFromWriter(t *testing.T) {
+	const text = "1,鈴木\n"
+	tests := []struct {
+		name    string
+		dialect Dialect
+		want    string
+	}{
+		{"utf-8", Dialect{}, text},
+		{"utf-8 bom", Dialect{BOM: true}, "\xEF\xBB\xBF" + text},
+		{"shift_jis", Dialect{Charset: ShiftJIS, BOM: true}, mustEncode(t, japanese.ShiftJIS.NewEncoder(), text)},
+		{"utf-16le bom", Dialect{Charset: UTF16LE, BOM: true}, mustEncode(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder(), text)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			e, err := NewEncoderFromWriter(buf, tt.dialect)
+			if err != nil {
+				t.Fatalf("NewEncoderFromWriter() error = %v", err)
+			}
+			if err := e.Encode(&testCharsetData{1, "鈴木"}); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if (e.closer != nil) != (tt.dialect.Charset != UTF8) {
+				t.Errorf("Encoder closes transformer = %v, want %v", e.closer != nil, tt.dialect.Charset != UTF8)
+			}
+			if err := e.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Encode() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDialect_Decode(t *testing.T) {
+	tsv := Dialect{Comma: '\t', Comment: '#', Header: true, TrimLeadingSpace: true}
+
+	d, err := NewDecoderFromReader(strings.NewReader("id\tname\n# comment\n1\t  foo\nx\tbar\n"), tsv)
+	if err != nil {
+		t.Fatalf("NewDecoderFromReader() error = %v", err)
+	}
+	var v testCharsetData
+	if err := d.Decode(&v); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if v.ID != 1 || v.Name != "foo" {
+		t.Errorf("Decode() = %v, want {1 foo}", v)
+	}
+	// the comment line is counted in physical lines
+	err = d.Decode(&v)
+	var derr *DecodeError
+	if !errors.As(err, &derr) || derr.Line != 4 || derr.Column != 1 || derr.Offset != 26 {
+		t.Errorf("Decode() error = %#v, want DecodeError at line 4, column 1, offset 26", err)
+	}
+
+	for _, dl := range []Dialect{{Quote: '「'}, {Quote: ';', Comma: ';'}, {Quote: '\'', Comma: '、'}} {
+		if _, err := NewDecoderFromReader(strings.NewReader(""), dl); err == nil {
+			t.Errorf("NewDecoderFromReader(%+v) error = nil, want invalid quote error", dl)
+		}
+	}
+}
+
+func TestDialect_quote(t *testing.T) {
+	dl := Dialect{Comma: ';', Quote: '\'', Header: true, UseCRLF: true}
+	values := []testCharsetData{{1, "it's"}, {2, "a;b"}, {3, "line\nbreak"}, {4, ` "x"`}}
+	const want = "id;name\r\n1;'it''s'\r\n2;'a;b'\r\n3;'line\r\nbreak'\r\n4;' \"x\"'\r\n"
+
+	buf := new(bytes.Buffer)
+	e, err := NewEncoderFromWriter(buf, dl)
+	if err != nil {
+		t.Fatalf("NewEncoderFromWriter() error = %v", err)
+	}
+	for _, v := range values {
+		if err := e.Encode(&v); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if buf.String() != want {
+		t.Fatalf("Encode() = %q, want %q", buf.String(), want)
+	}
+
+	d, err := NewDecoderFromReader(strings.NewReader(want), dl)
+	if err != nil {
+		t.Fatalf("NewDecoderFromReader() error = %v", err)
+	}
+	var got []testCharsetData
+	if _, err := d.DecodeAllContext(context.Background(), &got); err != nil {
+		t.Fatalf("DecodeAllContext() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, values) {
+		t.Errorf("Decode() = %+v, want %+v", got, values)
+	}
+
+	cd, err := NewChunkDecoder(strings.NewReader(want), int64(len(want)), dl,
+		func() interface{} { return new(testCharsetData) })
+	if err != nil {
+		t.Fatalf("NewChunkDecoder() error = %v", err)
+	}
+	cd.ChunkSize = 1
+	got = got[:0]
+	for r := range cd.Decode() {
+		if r.Err != nil {
+			t.Fatalf("ChunkDecoder result error = %v", r.Err)
+		}
+		got = append(got, *r.Value.(*testCharsetData))
+	}
+	if !reflect.DeepEqual(got, values) {
+		t.Errorf("ChunkDecoder.Decode() = %+v, want %+v", got, values)
+	}
+}
+
+func TestDialect_Encode(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e, err := NewEncoderFromWriter(buf, Dialect{Comma: '\t', UseCRLF: true, Header: true})
+	if err != nil {
+		t.Fatalf("NewEncoderFromWriter() error = %v", err)
+	}
+	for _, v := range []testCharsetData{{1, "foo"}, {2, "bar baz"}} {
+		if err := e.Encode(&v); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	}
+	e.Flush()
+	if want := "id\tname\r\n1\tfoo\r\n2\tbar baz\r\n"; buf.String() != want {
+		t.Errorf("Encode() = %q, want %q", buf.String(), want)
+	}
+}
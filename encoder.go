@@ -1,19 +1,30 @@
 package csve
 
 import (
+	"context"
+	"io"
 	"reflect"
 	"runtime"
+	"sync"
 	"time"
+	"unsafe"
 
 	"github.com/pkg/errors"
 )
 
+var (
+	beforeEncoderType = reflect.TypeOf((*BeforeEncoder)(nil)).Elem()
+	csvEncoderType    = reflect.TypeOf((*CSVEncoder)(nil)).Elem()
+)
+
 // CustomerEncoder inject your custom encode process.
 // Return true as ok if this logic handles the encode, otherwise Encode() will
 // fallback to default encode process.
 type CustomEncoder func(e *Encoder, v reflect.Value, format string) (ok bool, raw string, err error)
 
-// Encoder writes values into csv writer.
+// Encoder writes values into csv writer. Encode, Write, Flush, Error and
+// Close are safe for concurrent use; values are encoded concurrently and records are
+// written one at a time.
 type Encoder struct {
 	CsvWriter
 
@@ -22,14 +33,40 @@ type Encoder struct {
 
 	// Custom encoder to custiomize encoding process.
 	CustomEncoder CustomEncoder
+
+	// NullValue is written for nil pointer fields. Default is empty string.
+	NullValue string
+
+	// FlushEvery, if positive, flushes CsvWriter every FlushEvery records.
+	FlushEvery int
+
+	// FlushInterval, if positive, flushes CsvWriter within FlushInterval
+	// after a record is written.
+	FlushInterval time.Duration
+
+	mu     sync.Mutex
+	rows   int
+	timer  *time.Timer
+	closed bool
+	err    error // first error of flushes by FlushInterval
+
+	// header is true until the header line is written.
+	header bool
+
+	// closer is closed by Close to write trailing bytes of the charset
+	// transformer of NewEncoderFromWriter.
+	closer io.Closer
 }
 
+var errEncoderClosed = errors.New("encoder is closed")
+
 // NewEncoder returns a new Encoder which encodes values into csv writer.
 // If useHeader is true, Encoder writes csv header line.
 // NOTE: useHeder is not implemented yet.
 func NewEncoder(writer CsvWriter, useHeader bool) (*Encoder, error) {
 	return &Encoder{
-		writer, time.UTC, nil,
+		CsvWriter: writer,
+		Location:  time.UTC,
 	}, nil
 }
 
@@ -44,6 +81,10 @@ func (e *Encoder) Encode(v interface{}) (err error) {
 		}
 	}()
 
+	if ce, ok := v.(CSVEncoder); ok && e.CustomEncoder == nil {
+		return e.encodeGenerated(v, ce)
+	}
+
 	rv := reflect.ValueOf(v)
 	if rv.Kind() == reflect.Ptr {
 		if rv.IsNil() {
@@ -52,30 +93,252 @@ func (e *Encoder) Encode(v interface{}) (err error) {
 		rv = rv.Elem()
 	}
 
-	fields, err := getFields(rv.Type())
+	plan, err := getPlan(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	if reflect.PtrTo(rv.Type()).Implements(beforeEncoderType) {
+		// call the hook on a copy if v is passed by value
+		p := addr(rv)
+		if err := p.Interface().(BeforeEncoder).BeforeEncodeCSV(); err != nil {
+			return errors.Wrap(err, "before encode hook failed")
+		}
+		rv = p.Elem()
+	}
+
+	var record []string
+	if reflect.PtrTo(rv.Type()).Implements(csvEncoderType) && e.CustomEncoder == nil {
+		record, err = addr(rv).Interface().(CSVEncoder).EncodeCSV(e)
+	} else {
+		record, err = e.encodeFields(plan, rv)
+	}
 	if err != nil {
 		return err
 	}
 
+	return e.write(rv.Type(), record)
+}
+
+// encodeGenerated encodes v by its EncodeCSV method. The plan of v is only
+// needed for the header, so a record is encoded without reflection.
+func (e *Encoder) encodeGenerated(v interface{}, ce CSVEncoder) error {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return errors.New("invalid value type")
+	}
+
+	if h, ok := v.(BeforeEncoder); ok {
+		if err := h.BeforeEncodeCSV(); err != nil {
+			return errors.Wrap(err, "before encode hook failed")
+		}
+	}
+
+	record, err := ce.EncodeCSV(e)
+	if err != nil {
+		return err
+	}
+	return e.write(reflect.TypeOf(v), record)
+}
+
+// EncodeAllContext encodes each element of v, which is a slice or an array of
+// structs or pointers to structs. ctx is checked between elements, so a long
+// export can be cancelled. It returns the number of elements encoded.
+func (e *Encoder) EncodeAllContext(ctx context.Context, v interface{}) (n int, err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return 0, errors.New("invalid value type")
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		select {
+		case <-ctx.Done():
+			return n, ctx.Err()
+		default:
+		}
+
+		elem := rv.Index(i)
+		if elem.Kind() != reflect.Ptr && elem.CanAddr() {
+			elem = elem.Addr()
+		}
+		if err := e.Encode(elem.Interface()); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Write writes record as is, like Encode writes an encoded record. It does
+// not write the header line.
+func (e *Encoder) Write(record []string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return errEncoderClosed
+	}
+	if e.err != nil {
+		return e.err
+	}
+	return e.writeRecord(record)
+}
+
+// write writes the header if needed and record, and flushes by the policy.
+func (e *Encoder) write(t reflect.Type, record []string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return errEncoderClosed
+	}
+	if e.err != nil {
+		return e.err
+	}
+
+	if e.header {
+		plan, err := getPlan(t)
+		if err != nil {
+			return err
+		}
+		names := make([]string, len(plan.fields))
+		for i, f := range plan.fields {
+			names[i] = f.csvname
+		}
+		if err := e.CsvWriter.Write(names); err != nil {
+			return err
+		}
+		e.header = false
+	}
+	return e.writeRecord(record)
+}
+
+// writeRecord writes record and flushes by the policy. e.mu must be held.
+func (e *Encoder) writeRecord(record []string) error {
+	if err := e.CsvWriter.Write(record); err != nil {
+		return err
+	}
+	e.rows++
+
+	if e.FlushEvery > 0 && e.rows%e.FlushEvery == 0 {
+		e.CsvWriter.Flush()
+		return e.writerError()
+	}
+	if e.FlushInterval > 0 && e.timer == nil {
+		e.timer = time.AfterFunc(e.FlushInterval, func() {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.timer = nil
+			if !e.closed {
+				e.CsvWriter.Flush()
+				if err := e.writerError(); err != nil && e.err == nil {
+					e.err = err
+				}
+			}
+		})
+	}
+	return nil
+}
+
+// Flush flushes CsvWriter. Call Error to check if the flush succeeded.
+func (e *Encoder) Flush() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.CsvWriter.Flush()
+}
+
+// Error returns the error of a previous flush by FlushInterval, or the error
+// of CsvWriter like csv.Writer.Error.
+func (e *Encoder) Error() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err != nil {
+		return e.err
+	}
+	return e.writerError()
+}
+
+// Close flushes CsvWriter and returns its error, like csv.Writer.Error.
+// Encode returns error after Close. It does not close the underlying writer,
+// but writes trailing bytes of the charset of NewEncoderFromWriter.
+func (e *Encoder) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	e.CsvWriter.Flush()
+	err := e.err
+	if err == nil {
+		err = e.writerError()
+	}
+	if e.closer != nil {
+		if cerr := e.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// writerError returns the error of CsvWriter if it reports one.
+func (e *Encoder) writerError() error {
+	if w, ok := e.CsvWriter.(interface{ Error() error }); ok {
+		return w.Error()
+	}
+	return nil
+}
+
+// encodeFields encodes fields of the struct rv.
+func (e *Encoder) encodeFields(plan *plan, rv reflect.Value) ([]string, error) {
+	fields := plan.fields
+
+	// fields are accessed by offsets only if v is addressable
+	var base unsafe.Pointer
+	if rv.CanAddr() && e.CustomEncoder == nil {
+		base = rv.Addr().UnsafePointer()
+	}
+
 	encoded := make([]string, len(fields))
-	for i, f := range fields {
-		ref := rv.FieldByIndex(f.fieldindex)
+	for i := range fields {
+		f := &fields[i]
 
-		var ok bool
 		var err error
+		if op := &plan.ops[i]; op.enc != nil && base != nil {
+			encoded[i], err = op.enc(e, unsafe.Add(base, op.offset), f.csvformat)
+			if err != nil {
+				return nil, errors.Wrapf(err, "field %s encode failed", f.fieldname)
+			}
+			continue
+		}
+
+		ref := rv.FieldByIndex(f.fieldindex)
+		var ok bool
 		if e.CustomEncoder != nil {
 			ok, encoded[i], err = e.CustomEncoder(e, ref, f.csvformat)
 			if err != nil {
-				return errors.Errorf("field %s encode failed", f.fieldname)
+				return nil, errors.Wrapf(err, "field %s encode failed", f.fieldname)
 			}
 		}
 		if !ok {
 			encoded[i], err = f.enc(e, ref, f.csvformat)
 			if err != nil {
-				return errors.Errorf("field %s encode failed", f.fieldname)
+				return nil, errors.Wrapf(err, "field %s encode failed", f.fieldname)
 			}
 		}
 	}
 
-	return e.CsvWriter.Write(encoded)
+	return encoded, nil
+}
+
+// nullValue returns the value written for null value of the field.
+func (e *Encoder) nullValue(opts *tagOptions) string {
+	if opts.hasNull {
+		return opts.null
+	}
+	return e.NullValue
 }
@@ -2,10 +2,14 @@ package csve
 
 import (
 	"bytes"
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -169,3 +173,275 @@ func ExampleCustomEncoder() {
 
 	// Output: 5,Yuichi,N/A
 }
+
+func TestEncoder_Encode_null(t *testing.T) {
+	type data struct {
+		Int   *int     `csv:"0,int"`
+		Str   *string  `csv:"1,str"`
+		Float *float64 `csv:"2,float,,null=\\N"`
+	}
+	tests := []struct {
+		name      string
+		nullValue string
+		want      string
+	}{
+		{"default", "", ",,\\N\n"},
+		{"encoder null value", "NULL", "NULL,NULL,\\N\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			e, _ := NewEncoder(csv.NewWriter(buf), false)
+			e.NullValue = tt.nullValue
+			if err := e.Encode(&data{}); err != nil {
+				t.Fatalf("Encoder.Encode() error = %v", err)
+			}
+			e.Flush()
+			if buf.String() != tt.want {
+				t.Errorf("Encode() = %v, want %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEncoder_Encode_hook(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       interface{}
+		want    string
+		wantErr bool
+	}{
+		{"pointer", &testOrder{Qty: 2, Price: 1.5}, "2,1.5,3\n", false},
+		{"value", testOrder{Qty: 3, Price: 1.5}, "3,1.5,4.5\n", false},
+		{"error", &testOrder{Qty: -1}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			e, _ := NewEncoder(csv.NewWriter(buf), false)
+			if err := e.Encode(tt.v); (err != nil) != tt.wantErr {
+				t.Fatalf("Encoder.Encode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			e.Flush()
+			if buf.String() != tt.want {
+				t.Errorf("Encode() = %v, want %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEncoder_CsvWriter(t *testing.T) {
+	var b strings.Builder
+	e, _ := NewEncoder(csv.NewWriter(&b), false)
+	var w CsvWriter = e
+	w.Write([]string{"a", "b"})
+	w.Flush()
+	if err := e.Error(); err != nil || b.String() != "a,b\n" {
+		t.Errorf("Encoder as CsvWriter wrote %q, error = %v", b.String(), err)
+	}
+}
+
+func TestEncoder_concurrent(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e, _ := NewEncoder(csv.NewWriter(buf), false)
+	e.header = true
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var err error
+				if j%2 == 0 {
+					err = e.Encode(&testRecord{ID: i*100 + j, Name: "name"})
+				} else {
+					err = e.Write([]string{fmt.Sprint(i*100 + j), "name"})
+				}
+				if err != nil {
+					t.Errorf("Encoder.Encode() error = %v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := e.Close(); err != nil {
+		t.Fatalf("Encoder.Close() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 801 || lines[0] != "id,name" {
+		t.Errorf("Encode() wrote %d lines, header %q", len(lines), lines[0])
+	}
+}
+
+func TestEncoder_flush(t *testing.T) {
+	t.Run("every", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		e, _ := NewEncoder(csv.NewWriter(buf), false)
+		e.FlushEvery = 2
+
+		e.Encode(&testRecord{ID: 1})
+		if buf.Len() != 0 {
+			t.Errorf("flushed before FlushEvery records: %q", buf.String())
+		}
+		e.Encode(&testRecord{ID: 2})
+		if buf.String() != "1,\n2,\n" {
+			t.Errorf("flushed %q, want 2 records", buf.String())
+		}
+	})
+
+	t.Run("interval", func(t *testing.T) {
+		var mu sync.Mutex
+		buf := new(bytes.Buffer)
+		e, _ := NewEncoder(csv.NewWriter(writerFunc(func(p []byte) (int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return buf.Write(p)
+		})), false)
+		e.FlushInterval = 10 * time.Millisecond
+
+		e.Encode(&testRecord{ID: 1})
+		deadline := time.Now().Add(time.Second)
+		for {
+			mu.Lock()
+			got := buf.String()
+			mu.Unlock()
+			if got == "1,\n" {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("not flushed within interval: %q", got)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		e.Close()
+	})
+
+	t.Run("interval error", func(t *testing.T) {
+		werr := errors.New("disk full")
+		w := &onceErrorWriter{err: werr}
+		e, _ := NewEncoder(w, false)
+		e.FlushInterval = time.Millisecond
+
+		e.Encode(&testRecord{ID: 1})
+		deadline := time.Now().Add(time.Second)
+		for w.flushed() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("not flushed within interval")
+			}
+			time.Sleep(time.Millisecond)
+		}
+		// the writer does not report the error again
+		if err := e.Encode(&testRecord{ID: 2}); err != werr {
+			t.Errorf("Encoder.Encode() error = %v, want %v", err, werr)
+		}
+		e.Flush()
+		if err := e.Error(); err != werr {
+			t.Errorf("Encoder.Error() = %v, want %v", err, werr)
+		}
+		if err := e.Close(); err != werr {
+			t.Errorf("Encoder.Close() error = %v, want %v", err, werr)
+		}
+	})
+}
+
+// onceErrorWriter is a CsvWriter whose Error reports err once after Flush.
+type onceErrorWriter struct {
+	mu      sync.Mutex
+	err     error
+	flushes int
+}
+
+func (w *onceErrorWriter) Write(record []string) error {
+	return nil
+}
+
+func (w *onceErrorWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushes++
+}
+
+func (w *onceErrorWriter) Error() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.flushes != 1 {
+		return nil
+	}
+	err := w.err
+	w.err = nil
+	return err
+}
+
+func (w *onceErrorWriter) flushed() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flushes
+}
+
+func TestEncoder_Close(t *testing.T) {
+	werr := errors.New("disk full")
+	e, _ := NewEncoder(csv.NewWriter(writerFunc(func(p []byte) (int, error) {
+		return 0, werr
+	})), false)
+
+	if err := e.Encode(&testRecord{ID: 1}); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	if err := e.Close(); err != werr {
+		t.Errorf("Encoder.Close() error = %v, want %v", err, werr)
+	}
+	if err := e.Encode(&testRecord{ID: 2}); err == nil {
+		t.Errorf("Encoder.Encode() after Close error = nil, want error")
+	}
+
+	// the transformer of NewEncoderFromWriter is closed after flush
+	cerr := errors.New("incomplete sequence")
+	var closed []string
+	buf := new(bytes.Buffer)
+	e, _ = NewEncoder(csv.NewWriter(buf), false)
+	e.closer = closerFunc(func() error {
+		closed = append(closed, buf.String())
+		return cerr
+	})
+	e.Encode(&testRecord{ID: 1})
+	if err := e.Close(); err != cerr || len(closed) != 1 || closed[0] != "1,\n" {
+		t.Errorf("Encoder.Close() error = %v, closed after %q, want %v after flush", err, closed, cerr)
+	}
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestEncoder_EncodeAllContext(t *testing.T) {
+	values := []testRecord{{1, "a"}, {2, "b"}, {3, "c"}}
+
+	buf := new(bytes.Buffer)
+	e, _ := NewEncoder(csv.NewWriter(buf), false)
+	if n, err := e.EncodeAllContext(context.Background(), values); err != nil || n != 3 {
+		t.Fatalf("EncodeAllContext() = %v, %v, want 3, nil", n, err)
+	}
+	if n, err := e.EncodeAllContext(context.Background(), [1]*testRecord{{4, "d"}}); err != nil || n != 1 {
+		t.Fatalf("EncodeAllContext() = %v, %v, want 1, nil", n, err)
+	}
+	e.Flush()
+	if want := "1,a\n2,b\n3,c\n4,d\n"; buf.String() != want {
+		t.Errorf("EncodeAllContext() = %q, want %q", buf.String(), want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if n, err := e.EncodeAllContext(ctx, values); err != context.Canceled || n != 0 {
+		t.Errorf("EncodeAllContext() = %v, %v, want 0, %v", n, err, context.Canceled)
+	}
+}
@@ -0,0 +1,95 @@
+package csve
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RangeError is returned when a decoded value does not fit into the field type,
+// like 300 into int8. Use clamp tag option to saturate the value instead.
+type RangeError struct {
+	Field string
+	Raw   string
+	Type  reflect.Type
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("value %q of field %s is out of range for %s", e.Raw, e.Field, e.Type)
+}
+
+// ColumnError is returned when a record lacks required cells, or when the
+// number of columns differs from the struct with Decoder.StrictColumns.
+type ColumnError struct {
+	Line    int
+	Got     int      // columns in the record
+	Want    int      // columns expected by the struct, 0 if not checked
+	Missing []string // csv names of missing or empty required columns
+}
+
+func (e *ColumnError) Error() string {
+	msg := fmt.Sprintf("line %d:", e.Line)
+	if len(e.Missing) > 0 {
+		msg += fmt.Sprintf(" missing columns %s", strings.Join(e.Missing, ", "))
+	}
+	if e.Want > 0 && e.Got != e.Want {
+		msg += fmt.Sprintf(" got %d columns, want %d", e.Got, e.Want)
+	}
+	return msg
+}
+
+// DecodeError is returned when a cell cannot be converted into the field.
+// Err is the cause, like *RangeError or *strconv.NumError.
+//
+// If CsvReader is a CsvPositionReader, like csv.Reader and Tokenizer, Line
+// and Column are the physical line and 1-based byte column where the cell
+// begins, and Offset is the byte offset of the cell in the input. With
+// csv.Reader, Offset is that of the record if the cell is on a later line of
+// a multi-line record, and -1 if comment or empty lines precede the record.
+// Otherwise Line is the number of records read, Column is 0 and Offset is -1.
+//
+// Offsets of a Decoder by NewDecoderFromReader count bytes of the text after
+// the byte order mark is stripped and the charset is transcoded into UTF-8.
+type DecodeError struct {
+	Line   int
+	Column int
+	Offset int64
+	Field  string
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("field %s parse failed (%s): %v", e.Field, position(e.Line, e.Column), e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the cause for github.com/pkg/errors.
+func (e *DecodeError) Cause() error {
+	return e.Err
+}
+
+// ValidationError is returned when a decoded value violates constraints given
+// by tag options like min, max, minlen, maxlen, pattern and oneof.
+// Line, Column and Offset are the position of the cell like DecodeError.
+type ValidationError struct {
+	Line   int
+	Column int
+	Offset int64
+	Field  string
+	Raw    string
+	Rule   string // violated rule like "max=10"
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("field %s value %q violates %s (%s)", e.Field, e.Raw, e.Rule, position(e.Line, e.Column))
+}
+
+func position(line, column int) string {
+	if column > 0 {
+		return fmt.Sprintf("line:%d, column:%d", line, column)
+	}
+	return fmt.Sprintf("line:%d", line)
+}
@@ -0,0 +1,4 @@
+package csve
+
+// TestReaders exports testReaders for tests of generated code in csve_test.
+var TestReaders = testReaders
@@ -3,7 +3,9 @@ package csve
 import (
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,32 +17,157 @@ var fieldCache sync.Map
 type fieldDecoder func(d *Decoder, v reflect.Value, raw, format string) error
 
 func intDecoder(d *Decoder, v reflect.Value, raw, format string) error {
-	n, err := strconv.ParseInt(raw, 10, 64)
-	if err != nil || v.OverflowInt(n) {
+	return setInt(v, raw, 10, false)
+}
+
+func setInt(v reflect.Value, raw string, base int, clamp bool) error {
+	n, err := strconv.ParseInt(raw, base, 64)
+	if err != nil && !isRangeError(err) {
 		return err
 	}
+	if err != nil || v.OverflowInt(n) {
+		if !clamp {
+			return &RangeError{Raw: raw, Type: v.Type()}
+		}
+		max := int64(math.MaxInt64 >> uint(64-v.Type().Bits()))
+		if n > max {
+			n = max
+		} else if n < -max-1 {
+			n = -max - 1
+		}
+	}
 	v.SetInt(n)
 	return nil
 }
 
 func uintDecoder(d *Decoder, v reflect.Value, raw, format string) error {
-	n, err := strconv.ParseUint(raw, 10, 64)
-	if err != nil || v.OverflowUint(n) {
+	return setUint(v, raw, 10, false)
+}
+
+func setUint(v reflect.Value, raw string, base int, clamp bool) error {
+	n, err := strconv.ParseUint(raw, base, 64)
+	if err != nil && !isRangeError(err) {
+		if _, ierr := strconv.ParseInt(raw, base, 64); ierr == nil || isRangeError(ierr) {
+			// negative value
+			if !clamp {
+				return &RangeError{Raw: raw, Type: v.Type()}
+			}
+			v.SetUint(0)
+			return nil
+		}
 		return err
 	}
+	if err != nil || v.OverflowUint(n) {
+		if !clamp {
+			return &RangeError{Raw: raw, Type: v.Type()}
+		}
+		n = math.MaxUint64 >> uint(64-v.Type().Bits())
+	}
 	v.SetUint(n)
 	return nil
 }
 
+func isRangeError(err error) bool {
+	nerr, ok := err.(*strconv.NumError)
+	return ok && nerr.Err == strconv.ErrRange
+}
+
+// normalizeInt converts raw into the form strconv accepts based on tag options.
+func normalizeInt(raw string, nf *numberFormat, is *intSyntax) (string, error) {
+	if nf != nil {
+		var err error
+		if raw, _, err = nf.normalize(raw); err != nil {
+			return "", err
+		}
+	}
+	if is != nil {
+		return is.normalize(raw)
+	}
+	return raw, nil
+}
+
+func optionIntDecoder(opts *tagOptions) fieldDecoder {
+	nf, is, clamp := opts.num, opts.ints, opts.clamp
+	base := is.radix()
+	return func(d *Decoder, v reflect.Value, raw, format string) error {
+		s, err := normalizeInt(raw, nf, is)
+		if err != nil {
+			return err
+		}
+		return setInt(v, s, base, clamp)
+	}
+}
+
+func optionUintDecoder(opts *tagOptions) fieldDecoder {
+	nf, is, clamp := opts.num, opts.ints, opts.clamp
+	base := is.radix()
+	return func(d *Decoder, v reflect.Value, raw, format string) error {
+		s, err := normalizeInt(raw, nf, is)
+		if err != nil {
+			return err
+		}
+		return setUint(v, s, base, clamp)
+	}
+}
+
 func floatDecoder(d *Decoder, v reflect.Value, raw, format string) error {
+	return setFloat(v, raw, false)
+}
+
+func setFloat(v reflect.Value, raw string, clamp bool) error {
 	n, err := strconv.ParseFloat(raw, 64)
-	if err != nil || v.OverflowFloat(n) {
+	if err != nil && !isRangeError(err) {
 		return err
 	}
+	if err != nil || v.OverflowFloat(n) {
+		if !clamp {
+			return &RangeError{Raw: raw, Type: v.Type()}
+		}
+		max := math.MaxFloat64
+		if v.Kind() == reflect.Float32 {
+			max = math.MaxFloat32
+		}
+		n = math.Copysign(max, n)
+	}
 	v.SetFloat(n)
 	return nil
 }
 
+func optionFloatDecoder(opts *tagOptions) fieldDecoder {
+	nf, clamp := opts.num, opts.clamp
+	return func(d *Decoder, v reflect.Value, raw, format string) error {
+		s := raw
+		if nf != nil {
+			var pct bool
+			var err error
+			if s, pct, err = nf.normalize(raw); err != nil {
+				return err
+			}
+			if pct {
+				if strings.ContainsAny(s, "eE") {
+					n, err := strconv.ParseFloat(s, 64)
+					if err != nil {
+						return err
+					}
+					s = strconv.FormatFloat(n/100, 'g', -1, 64)
+				} else {
+					s = shiftPoint(s, -2)
+				}
+			}
+		}
+		return setFloat(v, s, clamp)
+	}
+}
+
+func boolDecoder(d *Decoder, v reflect.Value, raw, format string) error {
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return err
+	}
+	v.SetBool(b)
+	return nil
+}
+
 func stringDecoder(d *Decoder, v reflect.Value, raw, format string) error {
 	v.SetString(raw)
 	return nil
@@ -66,12 +193,54 @@ func stringEncoder(e *Encoder, v reflect.Value, format string) (raw string, err
 	return v.String(), nil
 }
 
+func boolEncoder(e *Encoder, v reflect.Value, format string) (raw string, err error) {
+	return strconv.FormatBool(v.Bool()), nil
+}
+
 func intEncoder(e *Encoder, v reflect.Value, format string) (raw string, err error) {
 	return strconv.FormatInt(v.Int(), 10), nil
 }
 
-func vEncoder(e *Encoder, v reflect.Value, format string) (raw string, err error) {
-	return fmt.Sprintf("%v", v), nil
+func uintEncoder(e *Encoder, v reflect.Value, format string) (raw string, err error) {
+	return strconv.FormatUint(v.Uint(), 10), nil
+}
+
+func floatEncoder(e *Encoder, v reflect.Value, format string) (raw string, err error) {
+	return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+}
+
+func optionIntEncoder(nf *numberFormat, is *intSyntax) fieldEncoder {
+	base := is.radix()
+	return func(e *Encoder, v reflect.Value, format string) (string, error) {
+		return formatInt(strconv.FormatInt(v.Int(), base), nf, is), nil
+	}
+}
+
+func optionUintEncoder(nf *numberFormat, is *intSyntax) fieldEncoder {
+	base := is.radix()
+	return func(e *Encoder, v reflect.Value, format string) (string, error) {
+		return formatInt(strconv.FormatUint(v.Uint(), base), nf, is), nil
+	}
+}
+
+func formatInt(s string, nf *numberFormat, is *intSyntax) string {
+	if is != nil {
+		s = is.format(s)
+	}
+	if nf != nil {
+		s = nf.format(s)
+	}
+	return s
+}
+
+func numberEncoder(nf *numberFormat, enc fieldEncoder) fieldEncoder {
+	return func(e *Encoder, v reflect.Value, format string) (string, error) {
+		raw, err := enc(e, v, format)
+		if err != nil {
+			return "", err
+		}
+		return nf.format(raw), nil
+	}
 }
 
 func timeEncoder(e *Encoder, v reflect.Value, format string) (raw string, err error) {
@@ -89,6 +258,218 @@ type field struct {
 	csvname   string
 	csvindex  int
 	csvformat string
+	csvopts   tagOptions
+
+	// retains is true if the decoded value may refer to the raw cell, so
+	// the cell must be copied when it is borrowed from CsvBytesReader.
+	retains bool
+
+	// interns is true for string and *string fields, whose values are
+	// interned by Decoder.DecodeInto.
+	interns bool
+}
+
+// tagOptions holds options written after the format in csv tag.
+// e.g. `csv:"0,amount,,thousands=,,precision=2"`
+type tagOptions struct {
+	num   *numberFormat
+	ints  *intSyntax
+	clamp bool
+	bits  uint
+
+	scale    int32
+	hasScale bool
+
+	null    string
+	hasNull bool
+
+	def        string
+	hasDefault bool
+
+	required bool
+
+	valid *validation
+
+	norm Normalization
+}
+
+func (o *tagOptions) number() *numberFormat {
+	if o.num == nil {
+		o.num = newNumberFormat()
+	}
+	return o.num
+}
+
+func (o *tagOptions) integer() *intSyntax {
+	if o.ints == nil {
+		o.ints = &intSyntax{base: 10}
+	}
+	return o.ints
+}
+
+type tagOptionParser func(o *tagOptions, value string) error
+
+var tagOptionParsers = map[string]tagOptionParser{
+	"thousands": func(o *tagOptions, value string) error {
+		if value == "" {
+			return errors.New("thousands requires a separator")
+		}
+		o.number().thousands = value
+		return nil
+	},
+	"decimal": func(o *tagOptions, value string) error {
+		if value == "" {
+			return errors.New("decimal requires a separator")
+		}
+		o.number().decimal = value
+		return nil
+	},
+	"precision": func(o *tagOptions, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid precision %q", value)
+		}
+		o.number().precision = n
+		return nil
+	},
+	"round": func(o *tagOptions, value string) error {
+		mode, ok := roundingModeNames[value]
+		if !ok {
+			return fmt.Errorf("unknown rounding mode %q", value)
+		}
+		o.number().round, o.number().hasRound = mode, true
+		return nil
+	},
+	"bits": func(o *tagOptions, value string) error {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || n == 0 {
+			return fmt.Errorf("invalid bits %q", value)
+		}
+		o.bits = uint(n)
+		return nil
+	},
+	"scale": func(o *tagOptions, value string) error {
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid scale %q", value)
+		}
+		o.scale, o.hasScale = int32(n), true
+		return nil
+	},
+	"null": func(o *tagOptions, value string) error {
+		o.null, o.hasNull = value, true
+		return nil
+	},
+	"default": func(o *tagOptions, value string) error {
+		o.def, o.hasDefault = value, true
+		return nil
+	},
+	"required": flagOption(func(o *tagOptions) {
+		o.required = true
+	}),
+	"min": func(o *tagOptions, value string) (err error) {
+		o.validation().min, err = parseBound(value)
+		o.validation().minText = value
+		return
+	},
+	"max": func(o *tagOptions, value string) (err error) {
+		o.validation().max, err = parseBound(value)
+		o.validation().maxText = value
+		return
+	},
+	"minlen": func(o *tagOptions, value string) (err error) {
+		o.validation().minlen, err = strconv.Atoi(value)
+		return
+	},
+	"maxlen": func(o *tagOptions, value string) (err error) {
+		o.validation().maxlen, err = strconv.Atoi(value)
+		return
+	},
+	"pattern": func(o *tagOptions, value string) (err error) {
+		o.validation().pattern, err = regexp.Compile(value)
+		return
+	},
+	"oneof": func(o *tagOptions, value string) error {
+		o.validation().oneof = strings.Split(value, "|")
+		return nil
+	},
+	"trim":     normalizationOption(TrimSpace),
+	"collapse": normalizationOption(CollapseSpace),
+	"upper":    normalizationOption(UpperCase),
+	"lower":    normalizationOption(LowerCase),
+	"nfc":      normalizationOption(NFC),
+	"nfkc":     normalizationOption(NFKC),
+	"percent": flagOption(func(o *tagOptions) {
+		o.number().percent = true
+	}),
+	"accounting": flagOption(func(o *tagOptions) {
+		o.number().accounting = true
+	}),
+	"base": func(o *tagOptions, value string) error {
+		switch value {
+		case "2", "8", "10", "16":
+			o.integer().base, _ = strconv.Atoi(value)
+			return nil
+		}
+		return fmt.Errorf("unsupported base %q", value)
+	},
+	"prefix": flagOption(func(o *tagOptions) {
+		o.integer().prefix = true
+	}),
+	"underscores": flagOption(func(o *tagOptions) {
+		o.integer().underscores = true
+	}),
+	"clamp": flagOption(func(o *tagOptions) {
+		o.clamp = true
+	}),
+}
+
+func flagOption(set func(o *tagOptions)) tagOptionParser {
+	return func(o *tagOptions, value string) error {
+		if value != "" {
+			return fmt.Errorf("unexpected value %q", value)
+		}
+		set(o)
+		return nil
+	}
+}
+
+func normalizationOption(n Normalization) tagOptionParser {
+	return flagOption(func(o *tagOptions) {
+		o.norm |= n
+	})
+}
+
+// parseTagOptions parses options like "key" or "key=value".
+// Since values may contain comma (e.g. thousands=,), a segment which does not
+// start with a known option continues the value of the previous option.
+func parseTagOptions(segs []string) (o tagOptions, err error) {
+	var keys, values []string
+	for _, seg := range segs {
+		key := seg
+		if i := strings.IndexByte(seg, '='); i >= 0 {
+			key = seg[:i]
+		}
+		if _, ok := tagOptionParsers[key]; ok {
+			keys = append(keys, key)
+			values = append(values, strings.TrimPrefix(seg[len(key):], "="))
+			continue
+		}
+		if len(values) == 0 {
+			return o, fmt.Errorf("unknown tag option %q", seg)
+		}
+		values[len(values)-1] += "," + seg
+	}
+
+	for i, key := range keys {
+		if err := tagOptionParsers[key](&o, values[i]); err != nil {
+			return o, fmt.Errorf("tag option %s: %v", key, err)
+		}
+	}
+	if nf := o.num; nf != nil && nf.thousands == nf.decimalSep() {
+		return o, fmt.Errorf("tag option thousands: %q is also the decimal separator", nf.thousands)
+	}
+	return o, nil
 }
 
 func getFields(t reflect.Type) (fields []field, err error) {
@@ -120,9 +501,23 @@ func getFields(t reflect.Type) (fields []field, err error) {
 			format = tags[2]
 		}
 
+		var opts tagOptions
+		if len(tags) >= 4 {
+			opts, err = parseTagOptions(tags[3:])
+			if err == nil {
+				err = checkTagOptions(f.Type, &opts)
+			}
+			if err == nil && opts.valid != nil {
+				err = opts.valid.compile(f.Type)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %v", f.Name, err)
+			}
+		}
+
 		var dec fieldDecoder
 		var enc fieldEncoder
-		dec, enc, err = getFieldEncoder(f.Type)
+		dec, enc, err = getFieldEncoder(f.Type, &opts)
 		if err != nil {
 			return nil, err
 		}
@@ -136,6 +531,9 @@ func getFields(t reflect.Type) (fields []field, err error) {
 			csvname:    tags[1],
 			csvindex:   int(index),
 			csvformat:  format,
+			csvopts:    opts,
+			retains:    retainsRaw(f.Type),
+			interns:    isStringType(f.Type),
 		})
 	}
 
@@ -143,15 +541,115 @@ func getFields(t reflect.Type) (fields []field, err error) {
 	return
 }
 
-func getFieldEncoder(t reflect.Type) (dec fieldDecoder, enc fieldEncoder, err error) {
+// isStringType reports whether t is a string kind or a pointer to it.
+func isStringType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.String
+}
+
+// isIntegerType reports whether t is an integer kind or big.Int.
+func isIntegerType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return t == bigIntType
+}
+
+// retainsRaw reports whether a decoded value of t may refer to the raw cell.
+// A string does, and time.Time does for its zone name.
+func retainsRaw(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isSQLNullType(t) {
+		return retainsRaw(t.Field(0).Type)
+	}
+	if isSQLType(t) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128,
+		reflect.Bool:
+		return false
+	}
+	switch t {
+	case bigIntType, bigFloatType, bigRatType, decimalType:
+		return false
+	}
+	return true
+}
+
+// checkTagOptions checks if tag options are applicable to t. Like validation,
+// pointer and sql.Null* fields are checked by their element type.
+func checkTagOptions(t reflect.Type, opts *tagOptions) error {
+	if opts.hasNull && t.Kind() != reflect.Ptr && !isSQLNullType(t) && !isSQLType(t) {
+		return fmt.Errorf("null is not supported for %s", t)
+	}
+	et := validationType(t)
+	if nf := opts.num; nf != nil {
+		if !isNumericType(et) && et.Kind() != reflect.Complex64 && et.Kind() != reflect.Complex128 {
+			return fmt.Errorf("number format is not supported for %s", et)
+		}
+		if nf.hasRound && nf.precision < 0 && !opts.hasScale {
+			return errors.New("round requires precision or scale")
+		}
+	}
+	if opts.ints != nil && !isIntegerType(et) {
+		return fmt.Errorf("base, prefix and underscores are not supported for %s", et)
+	}
+	if opts.clamp {
+		switch et.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return fmt.Errorf("clamp is not supported for %s", et)
+		}
+	}
+	if opts.bits > 0 && et != bigFloatType {
+		return fmt.Errorf("bits is not supported for %s", et)
+	}
+	if opts.hasScale && et != decimalType {
+		return fmt.Errorf("scale is not supported for %s", et)
+	}
+	return nil
+}
+
+func checkIntOptions(opts *tagOptions) error {
+	if nf := opts.num; nf != nil {
+		if nf.percent || nf.precision >= 0 {
+			return errors.New("percent and precision are only supported for float fields")
+		}
+		if opts.ints != nil && opts.ints.base != 10 {
+			return errors.New("number format is only supported for base 10")
+		}
+	}
+	return nil
+}
+
+func getFieldEncoder(t reflect.Type, opts *tagOptions) (dec fieldDecoder, enc fieldEncoder, err error) {
+	if isSQLNullType(t) {
+		return getSQLNullFieldEncoder(t, opts)
+	}
+	if isSQLType(t) {
+		return getSQLFieldEncoder(t, opts)
+	}
+
 	switch t.Kind() {
 	case reflect.Ptr:
 		var rdec fieldDecoder
 		var renc fieldEncoder
-		rdec, renc, err = getFieldEncoder(t.Elem())
+		rdec, renc, err = getFieldEncoder(t.Elem(), opts)
 		if err == nil {
 			dec = func(d *Decoder, v reflect.Value, raw, format string) error {
-				if raw == "" {
+				if d.isNull(raw, opts) {
 					v.Set(reflect.Zero(v.Type()))
 					return nil
 				} else {
@@ -164,7 +662,7 @@ func getFieldEncoder(t reflect.Type) (dec fieldDecoder, enc fieldEncoder, err er
 			}
 			enc = func(e *Encoder, v reflect.Value, format string) (string, error) {
 				if v.IsNil() {
-					return "", nil
+					return e.nullValue(opts), nil
 				}
 				v = v.Elem()
 				return renc(e, v, format)
@@ -173,20 +671,65 @@ func getFieldEncoder(t reflect.Type) (dec fieldDecoder, enc fieldEncoder, err er
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		dec = intDecoder
 		enc = intEncoder
+		if opts.num != nil || opts.ints != nil || opts.clamp {
+			if err = checkIntOptions(opts); err != nil {
+				return
+			}
+			dec = optionIntDecoder(opts)
+			enc = optionIntEncoder(opts.num, opts.ints)
+		}
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		dec = uintDecoder
-		enc = vEncoder
+		enc = uintEncoder
+		if opts.num != nil || opts.ints != nil || opts.clamp {
+			if err = checkIntOptions(opts); err != nil {
+				return
+			}
+			dec = optionUintDecoder(opts)
+			enc = optionUintEncoder(opts.num, opts.ints)
+		}
+	case reflect.Bool:
+		dec = boolDecoder
+		enc = boolEncoder
 	case reflect.String:
 		dec = stringDecoder
 		enc = stringEncoder
 	case reflect.Float32, reflect.Float64:
 		dec = floatDecoder
-		enc = vEncoder
+		enc = floatEncoder
+		if opts.num != nil || opts.clamp {
+			dec = optionFloatDecoder(opts)
+		}
+		if nf := opts.num; nf != nil {
+			enc = numberEncoder(nf, floatEncoder)
+		}
+	case reflect.Complex64, reflect.Complex128:
+		if nf := opts.num; nf != nil && (nf.thousands != "" || nf.decimal != "" || nf.percent || nf.accounting) {
+			return nil, nil, errors.New("only precision is supported for complex fields")
+		}
+		dec = complexDecoder
+		enc = complexEncoder(opts)
 	case reflect.Struct:
-		if t == timeType {
+		switch t {
+		case timeType:
 			dec = timeDecoder
 			enc = timeEncoder
-		} else {
+		case bigIntType:
+			if err = checkIntOptions(opts); err != nil {
+				return
+			}
+			dec = bigIntDecoder(opts)
+			enc = bigIntEncoder(opts)
+		case bigFloatType:
+			dec = bigFloatDecoder(opts)
+			enc = bigFloatEncoder(opts)
+		case bigRatType:
+			dec = bigRatDecoder(opts)
+			enc = bigRatEncoder(opts)
+		case decimalType:
+			dec = decimalDecoder(opts)
+			enc = decimalEncoder(opts)
+		default:
 			err = errors.New("no field decoder found")
 		}
 	default:
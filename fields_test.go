@@ -1,6 +1,7 @@
 package csve
 
 import (
+	"database/sql"
 	"reflect"
 	"testing"
 	"time"
@@ -29,6 +30,8 @@ func Test_getFields(t *testing.T) {
 					fieldname:  "Str",
 					csvname:    "str",
 					csvindex:   0,
+					retains:    true,
+					interns:    true,
 				},
 				{
 					typ:        reflect.TypeOf(int(0)),
@@ -86,6 +89,7 @@ func Test_getFields(t *testing.T) {
 					csvname:    "time",
 					csvindex:   8,
 					csvformat:  "2006-01-02T15:04:05",
+					retains:    true,
 				},
 			},
 		},
@@ -108,3 +112,31 @@ func Test_getFields(t *testing.T) {
 		})
 	}
 }
+
+func Test_getFields_null(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       interface{}
+		wantErr bool
+	}{
+		{"pointer", struct {
+			V *int `csv:"0,v,,null=NULL"`
+		}{}, false},
+		{"sql.NullInt64", struct {
+			V sql.NullInt64 `csv:"0,v,,null=NULL"`
+		}{}, false},
+		{"int", struct {
+			V int `csv:"0,v,,null=NULL"`
+		}{}, true},
+		{"time.Time", struct {
+			V time.Time `csv:"0,v,,null=NULL"`
+		}{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := getFields(reflect.TypeOf(tt.v)); (err != nil) != tt.wantErr {
+				t.Errorf("getFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package csve
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Functions in this file are called by DecodeCSV and EncodeCSV methods
+// generated by csvegen. They convert a cell the same way as Decode and
+// Encode do for a field without tag options, without reflection.
+
+// DecodeInt decodes the cell at index of record into p. name is the field
+// name reported in errors.
+func DecodeInt[T ~int | ~int8 | ~int16 | ~int32 | ~int64](d *Decoder, p *T, record []string, index int, name string) error {
+	return decodeCell(d, p, record, index, name, func(p *T, raw string) error {
+		return decodeInt(p, raw, nil)
+	})
+}
+
+// DecodeUint decodes the cell at index of record into p.
+func DecodeUint[T ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64](d *Decoder, p *T, record []string, index int, name string) error {
+	return decodeCell(d, p, record, index, name, func(p *T, raw string) error {
+		return decodeUint(p, raw, nil)
+	})
+}
+
+// DecodeFloat decodes the cell at index of record into p.
+func DecodeFloat[T ~float32 | ~float64](d *Decoder, p *T, record []string, index int, name string) error {
+	return decodeCell(d, p, record, index, name, func(p *T, raw string) error {
+		return decodeFloat(p, raw, nil)
+	})
+}
+
+// DecodeBool decodes the cell at index of record into p.
+func DecodeBool[T ~bool](d *Decoder, p *T, record []string, index int, name string) error {
+	return decodeCell(d, p, record, index, name, func(p *T, raw string) error {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		*p = T(b)
+		return nil
+	})
+}
+
+// DecodeString decodes the cell at index of record into p.
+func DecodeString[T ~string](d *Decoder, p *T, record []string, index int, name string) error {
+	raw := d.genCell(record, index)
+	if d.interning {
+		raw = d.internString(raw)
+	} else if d.borrowed {
+		raw = strings.Clone(raw)
+	}
+	*p = T(raw)
+	return nil
+}
+
+// DecodeTime decodes the cell at index of record into p with format.
+func DecodeTime(d *Decoder, p *time.Time, record []string, index int, format, name string) error {
+	return decodeCell(d, p, record, index, name, func(p *time.Time, raw string) error {
+		return decodeTime(d, p, raw, format)
+	})
+}
+
+// EncodeTime encodes t with format in Encoder.Location.
+func EncodeTime(e *Encoder, t time.Time, format string) string {
+	return t.In(e.Location).Format(format)
+}
+
+func decodeCell[T any](d *Decoder, p *T, record []string, index int, name string, dec func(p *T, raw string) error) error {
+	raw := d.genCell(record, index)
+	if raw == "" && d.EmptyAsZero {
+		var zero T
+		*p = zero
+		return nil
+	}
+	if err := dec(p, raw); err != nil {
+		return d.fieldError(err, name, raw, index, record)
+	}
+	return nil
+}
+
+// genCell returns normalized cell at index of record, or empty string if the
+// record does not have the column.
+func (d *Decoder) genCell(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return d.Normalization.apply(record[index])
+}
@@ -0,0 +1,8 @@
+module github.com/yuichi1004/csve
+
+go 1.22
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.21.0
+)
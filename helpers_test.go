@@ -0,0 +1,22 @@
+package csve
+
+import (
+	"encoding/csv"
+	"strings"
+)
+
+// testRecord is a simple record type shared by tests of Decoder, Encoder
+// and ParallelDecoder.
+type testRecord struct {
+	ID   int    `csv:"0,id"`
+	Name string `csv:"1,name"`
+}
+
+// testReaders returns constructors of each CsvReader implementation reading
+// raw, so a test can run against csv.Reader and Tokenizer.
+func testReaders(raw string) map[string]func() CsvReader {
+	return map[string]func() CsvReader{
+		"csv.Reader": func() CsvReader { return csv.NewReader(strings.NewReader(raw)) },
+		"Tokenizer":  func() CsvReader { return NewTokenizer(strings.NewReader(raw)) },
+	}
+}
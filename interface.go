@@ -5,8 +5,50 @@ type CsvReader interface {
 	Read() (record []string, err error)
 }
 
+// CsvBytesReader is a CsvReader which can read a record without allocation,
+// like Tokenizer. The returned slices are valid until the next read, and
+// Decoder copies cells only for fields which retain them, like string.
+type CsvBytesReader interface {
+	CsvReader
+	ReadBytes() (record [][]byte, err error)
+}
+
+// CsvPositionReader is a CsvReader which reports where the last record read
+// is in the input, like csv.Reader and Tokenizer. Decoder uses it to report
+// physical lines, columns and byte offsets in errors.
+type CsvPositionReader interface {
+	CsvReader
+	FieldPos(field int) (line, column int)
+	InputOffset() int64
+}
+
 // CsvWriter defines interfce for encoding.csv.Writer.
 type CsvWriter interface {
 	Write(record []string) error
 	Flush()
 }
+
+// AfterDecoder is implemented by a struct which needs post-process after
+// Decoder.Decode, like cross-field validation or derived fields.
+// line is the line number of the decoded record.
+type AfterDecoder interface {
+	AfterDecodeCSV(line int) error
+}
+
+// BeforeEncoder is implemented by a struct which needs pre-process before
+// Encoder.Encode, like computing derived fields.
+type BeforeEncoder interface {
+	BeforeEncodeCSV() error
+}
+
+// CSVDecoder is implemented by a struct with DecodeCSV method generated by
+// csvegen. Decoder prefers it to reflection unless CustomDecoder is set.
+type CSVDecoder interface {
+	DecodeCSV(d *Decoder, record []string) error
+}
+
+// CSVEncoder is implemented by a struct with EncodeCSV method generated by
+// csvegen. Encoder prefers it to reflection unless CustomEncoder is set.
+type CSVEncoder interface {
+	EncodeCSV(e *Encoder) (record []string, err error)
+}
@@ -0,0 +1,76 @@
+package csve
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization specifies how a cell is normalized before conversion.
+// Flags can be combined like TrimSpace|NFKC. Unicode normalization is applied
+// first, so full-width spaces normalized by NFKC are trimmed as well.
+type Normalization uint
+
+const (
+	// TrimSpace removes leading and trailing white spaces.
+	TrimSpace Normalization = 1 << iota
+	// CollapseSpace replaces each run of white spaces with a single space.
+	CollapseSpace
+	// UpperCase maps letters to upper case.
+	UpperCase
+	// LowerCase maps letters to lower case.
+	LowerCase
+	// NFC applies Unicode canonical composition.
+	NFC
+	// NFKC applies Unicode compatibility composition, which maps half-width
+	// kana to full-width and full-width alphanumerics to half-width.
+	NFKC
+)
+
+// apply returns normalized s.
+func (n Normalization) apply(s string) string {
+	if n == 0 {
+		return s
+	}
+
+	switch {
+	case n&NFKC != 0:
+		s = norm.NFKC.String(s)
+	case n&NFC != 0:
+		s = norm.NFC.String(s)
+	}
+	if n&TrimSpace != 0 {
+		s = strings.TrimSpace(s)
+	}
+	if n&CollapseSpace != 0 {
+		s = collapseSpace(s)
+	}
+	switch {
+	case n&UpperCase != 0:
+		s = strings.ToUpper(s)
+	case n&LowerCase != 0:
+		s = strings.ToLower(s)
+	}
+	return s
+}
+
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package csve
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalization_apply(t *testing.T) {
+	tests := []struct {
+		name string
+		n    Normalization
+		s    string
+		want string
+	}{
+		{"none", 0, " a  b ", " a  b "},
+		{"trim", TrimSpace, " a  b ", "a  b"},
+		{"collapse", CollapseSpace, "a \t\n b", "a b"},
+		{"trim and collapse", TrimSpace | CollapseSpace, "  a   b  ", "a b"},
+		{"upper", UpperCase, "abc", "ABC"},
+		{"lower", LowerCase, "ABC", "abc"},
+		{"nfc", NFC, "ガ", "ガ"},
+		{"nfkc half-width kana", NFKC, "ｶﾞｷﾞ", "ガギ"},
+		{"nfkc full-width alnum", NFKC, "ＡＢＣ１２３", "ABC123"},
+		{"nfkc full-width space", NFKC | TrimSpace, "　abc　", "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.n.apply(tt.s); got != tt.want {
+				t.Errorf("apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_normalization(t *testing.T) {
+	type data struct {
+		ID   int    `csv:"0,id,,required"`
+		Name string `csv:"1,name,,nfkc,collapse"`
+		Code string `csv:"2,code,,upper"`
+	}
+	tests := []struct {
+		name          string
+		raw           string
+		normalization Normalization
+		want          data
+		wantErr       bool
+	}{
+		{"field options", `１２,"ｽｽﾞｷ  ｲﾁﾛｳ",jp`, 0, data{}, true},
+		{"decoder normalization", `１２,"ｽｽﾞｷ  ｲﾁﾛｳ", jp `, NFKC | TrimSpace, data{12, "スズキ イチロウ", "JP"}, false},
+		{"required after trim", `  ,a,b`, TrimSpace, data{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(tt.raw)), false)
+			d.Normalization = tt.normalization
+			var got data
+			if err := d.Decode(&got); (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
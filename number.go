@@ -0,0 +1,372 @@
+package csve
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// numberFormat describes how a number is written in a csv cell.
+// It is built from tag options like thousands, decimal, precision, percent and
+// accounting, and applied symmetrically on decode and encode.
+type numberFormat struct {
+	thousands  string
+	decimal    string
+	precision  int
+	round      RoundingMode
+	hasRound   bool
+	percent    bool
+	accounting bool
+}
+
+func newNumberFormat() *numberFormat {
+	return &numberFormat{precision: -1}
+}
+
+// normalize converts formatted raw value into the plain form strconv accepts.
+// It returns true as pct if raw was written in percent.
+func (nf *numberFormat) normalize(raw string) (s string, pct bool, err error) {
+	s = strings.TrimSpace(raw)
+
+	neg := false
+	if nf.accounting && len(s) >= 2 && s[0] == '(' && s[len(s)-1] == ')' {
+		neg = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	if nf.percent {
+		s = strings.TrimSpace(strings.TrimSuffix(s, "%"))
+		pct = true
+	}
+	if nf.thousands != "" {
+		if s, err = nf.ungroup(s); err != nil {
+			return "", false, err
+		}
+	}
+	if decimal := nf.decimalSep(); decimal != "." {
+		s = strings.Replace(s, decimal, ".", -1)
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s, pct, nil
+}
+
+var errDigitGroup = errors.New("invalid digit grouping")
+
+// ungroup removes thousands separators from s. Separators must split the
+// integer part into groups of 3 digits after the first group.
+func (nf *numberFormat) ungroup(s string) (string, error) {
+	intPart, fracPart := s, ""
+	if i := strings.Index(s, nf.decimalSep()); i >= 0 {
+		intPart, fracPart = s[:i], s[i:]
+	}
+	if strings.Contains(fracPart, nf.thousands) {
+		return "", errDigitGroup
+	}
+	if !strings.Contains(intPart, nf.thousands) {
+		return s, nil
+	}
+
+	groups := strings.Split(strings.TrimLeft(intPart, "+-"), nf.thousands)
+	if n := len(groups[0]); n == 0 || n > 3 {
+		return "", errDigitGroup
+	}
+	for _, g := range groups[1:] {
+		if len(g) != 3 {
+			return "", errDigitGroup
+		}
+	}
+	return strings.Replace(intPart, nf.thousands, "", -1) + fracPart, nil
+}
+
+func (nf *numberFormat) decimalSep() string {
+	if nf.decimal == "" {
+		return "."
+	}
+	return nf.decimal
+}
+
+// format converts plain number s (like "-1234.5") into formatted value.
+// s must not be written in exponent notation.
+func (nf *numberFormat) format(s string) string {
+	if nf.percent {
+		s = shiftPoint(s, 2)
+	}
+	if nf.precision >= 0 {
+		s = roundPoint(s, nf.precision, nf.round)
+	}
+
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimLeft(s, "+-")
+	if strings.IndexFunc(s, isNotDigitOrPoint) >= 0 {
+		// NaN or Inf, nothing to format
+		if neg {
+			return "-" + s
+		}
+		return s
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	zero := strings.Trim(intPart+fracPart, "0") == ""
+	if nf.thousands != "" {
+		intPart = groupDigits(intPart, nf.thousands)
+	}
+
+	s = intPart
+	if fracPart != "" {
+		s += nf.decimalSep() + fracPart
+	}
+	if nf.percent {
+		s += "%"
+	}
+
+	if neg && !zero {
+		if nf.accounting {
+			return "(" + s + ")"
+		}
+		return "-" + s
+	}
+	return s
+}
+
+func isNotDigitOrPoint(r rune) bool {
+	return (r < '0' || r > '9') && r != '.'
+}
+
+func groupDigits(s, sep string) string {
+	if len(s) <= 3 {
+		return s
+	}
+	var b strings.Builder
+	head := len(s) % 3
+	if head > 0 {
+		b.WriteString(s[:head])
+	}
+	for i := head; i < len(s); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(s[i : i+3])
+	}
+	return b.String()
+}
+
+// shiftPoint moves the decimal point of plain number s by n digits to right.
+// A negative n moves it to left.
+func shiftPoint(s string, n int) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	if strings.IndexFunc(s, isNotDigitOrPoint) >= 0 {
+		return sign + s
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	digits := intPart + fracPart
+	point := len(intPart) + n
+	for point < 0 {
+		digits = "0" + digits
+		point++
+	}
+	for point > len(digits) {
+		digits += "0"
+	}
+
+	intPart = strings.TrimLeft(digits[:point], "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+	fracPart = strings.TrimRight(digits[point:], "0")
+	if fracPart == "" {
+		return sign + intPart
+	}
+	return sign + intPart + "." + fracPart
+}
+
+// roundPoint rounds plain number s to prec digits after the decimal point.
+func roundPoint(s string, prec int, mode RoundingMode) string {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return s
+	}
+	num := new(big.Int).Mul(r.Num(), pow10(prec))
+	return formatScaled(roundDiv(num, r.Denom(), mode), prec)
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// formatScaled formats n * 10^-scale as plain number.
+func formatScaled(n *big.Int, scale int) string {
+	digits := new(big.Int).Abs(n).String()
+	if scale <= 0 {
+		if n.Sign() < 0 {
+			return "-" + digits
+		}
+		return digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	s := digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	if n.Sign() < 0 {
+		return "-" + s
+	}
+	return s
+}
+
+// RoundingMode specifies how a value is rounded to the precision.
+// In tag, it is given like round=half_even.
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds halves away from zero. This is the default.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfDown rounds halves towards zero.
+	RoundHalfDown
+	// RoundHalfEven rounds halves to the even neighbor.
+	RoundHalfEven
+	// RoundUp rounds away from zero.
+	RoundUp
+	// RoundDown rounds towards zero.
+	RoundDown
+	// RoundCeiling rounds towards positive infinity.
+	RoundCeiling
+	// RoundFloor rounds towards negative infinity.
+	RoundFloor
+)
+
+var roundingModeNames = map[string]RoundingMode{
+	"half_up":   RoundHalfUp,
+	"half_down": RoundHalfDown,
+	"half_even": RoundHalfEven,
+	"up":        RoundUp,
+	"down":      RoundDown,
+	"ceiling":   RoundCeiling,
+	"floor":     RoundFloor,
+}
+
+// roundDiv returns num / den rounded by mode. den must be positive.
+func roundDiv(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+
+	neg := num.Sign() < 0
+	half := new(big.Int).Abs(r)
+	half.Lsh(half, 1)
+	cmp := half.Cmp(den)
+
+	var away bool
+	switch mode {
+	case RoundHalfUp:
+		away = cmp >= 0
+	case RoundHalfDown:
+		away = cmp > 0
+	case RoundHalfEven:
+		away = cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+	case RoundUp:
+		away = true
+	case RoundDown:
+		away = false
+	case RoundCeiling:
+		away = !neg
+	case RoundFloor:
+		away = neg
+	}
+	if away {
+		if neg {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// intSyntax describes how an integer is written in a csv cell.
+// It is built from tag options base, prefix and underscores.
+type intSyntax struct {
+	base        int
+	prefix      bool
+	underscores bool
+}
+
+// radix returns the base of integer. nil intSyntax means base 10.
+func (is *intSyntax) radix() int {
+	if is == nil {
+		return 10
+	}
+	return is.base
+}
+
+func basePrefix(base int) string {
+	switch base {
+	case 2:
+		return "0b"
+	case 8:
+		return "0o"
+	case 16:
+		return "0x"
+	}
+	return ""
+}
+
+// normalize strips optional base prefix and underscores from raw.
+func (is *intSyntax) normalize(raw string) (string, error) {
+	s := raw
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	prefixed := false
+	if p := basePrefix(is.base); p != "" && len(s) > len(p) && strings.EqualFold(s[:len(p)], p) {
+		s = s[len(p):]
+		prefixed = true
+	}
+	if is.underscores && strings.Contains(s, "_") {
+		if !validUnderscores(s, prefixed) {
+			return "", fmt.Errorf("invalid underscores in %q", raw)
+		}
+		s = strings.Replace(s, "_", "", -1)
+	}
+	return sign + s, nil
+}
+
+// validUnderscores reports whether underscores in s only separate digits as
+// Go integer literals do. An underscore may follow the base prefix.
+func validUnderscores(s string, prefixed bool) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			continue
+		}
+		if i == len(s)-1 || s[i+1] == '_' {
+			return false
+		}
+		if i == 0 && !prefixed {
+			return false
+		}
+	}
+	return true
+}
+
+// format adds base prefix to formatted integer s if required.
+func (is *intSyntax) format(s string) string {
+	if !is.prefix {
+		return s
+	}
+	if strings.HasPrefix(s, "-") {
+		return "-" + basePrefix(is.base) + s[1:]
+	}
+	return basePrefix(is.base) + s
+}
@@ -0,0 +1,292 @@
+package csve
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type TestNumberData struct {
+	Amount  float64 `csv:"0,amount,,thousands=,,precision=2"`
+	Euro    float64 `csv:"1,euro,,thousands=.,decimal=,,precision=2"`
+	Balance int     `csv:"2,balance,,thousands=,,accounting"`
+	Rate    float64 `csv:"3,rate,,percent,precision=1"`
+	Large   float64 `csv:"4,large"`
+}
+
+func TestNumberFormat_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want TestNumberData
+	}{
+		{
+			name: "positive case",
+			raw:  `"1,234.50","1.234,50","1,234",12.5%,12000000000000000000000` + "\n",
+			want: TestNumberData{1234.5, 1234.5, 1234, 0.125, 1.2e22},
+		},
+		{
+			name: "negative case",
+			raw:  `-12.00,"-0,50","(1,234)",-0.1%,-1` + "\n",
+			want: TestNumberData{-12, -0.5, -1234, -0.001, -1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(tt.raw)), false)
+			var got TestNumberData
+			if err := d.Decode(&got); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+
+			buf := new(bytes.Buffer)
+			e, _ := NewEncoder(csv.NewWriter(buf), false)
+			if err := e.Encode(&got); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			e.Flush()
+			if buf.String() != tt.raw {
+				t.Errorf("Encode() = %v, want %v", buf.String(), tt.raw)
+			}
+		})
+	}
+}
+
+func TestNumberFormat_grouping(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    float64
+		wantErr bool
+	}{
+		{"1,234,567.5", 1234567.5, false},
+		{"-12,345", -12345, false},
+		{"1234", 1234, false},
+		{"1,2,3,4", 0, true},
+		{"12,34", 0, true},
+		{"1234,567", 0, true},
+		{",123", 0, true},
+		{"1,234.5,6", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(strconv.Quote(tt.raw))), false)
+			var got struct {
+				V float64 `csv:"0,v,,thousands=,"`
+			}
+			err := d.Decode(&got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if err == nil && got.V != tt.want {
+				t.Errorf("Decode(%q) = %v, want %v", tt.raw, got.V, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumberFormat_percent(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    float64
+		wantErr bool
+	}{
+		{"12.5%", 0.125, false},
+		{"1.5e1%", 0.15, false},
+		{"yes%", 0, true},
+		{"hello%", 0, true},
+		{"1x%", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(tt.raw)), false)
+			var got struct {
+				V float64 `csv:"0,v,,percent"`
+			}
+			err := d.Decode(&got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if err == nil && got.V != tt.want {
+				t.Errorf("Decode(%q) = %v, want %v", tt.raw, got.V, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumberFormat_format(t *testing.T) {
+	tests := []struct {
+		name string
+		nf   numberFormat
+		s    string
+		want string
+	}{
+		{"plain", numberFormat{precision: -1}, "1234.5", "1234.5"},
+		{"thousands", numberFormat{thousands: ",", precision: -1}, "-1234567", "-1,234,567"},
+		{"precision rounds half away", numberFormat{precision: 2}, "2.675", "2.68"},
+		{"precision pads", numberFormat{precision: 2}, "3", "3.00"},
+		{"negative zero", numberFormat{precision: 1}, "-0.01", "0.0"},
+		{"accounting", numberFormat{accounting: true, precision: 2}, "-12", "(12.00)"},
+		{"percent", numberFormat{percent: true, precision: -1}, "0.125", "12.5%"},
+		{"decimal", numberFormat{thousands: " ", decimal: ",", precision: -1}, "1234.5", "1 234,5"},
+		{"nan", numberFormat{thousands: ",", precision: 2}, "NaN", "NaN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.nf.format(tt.s); got != tt.want {
+				t.Errorf("format() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_shiftPoint(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"12.5", -2, "0.125"},
+		{"-0.125", 2, "-12.5"},
+		{"7", -2, "0.07"},
+		{"1.5", 3, "1500"},
+		{"0", 2, "0"},
+	}
+	for _, tt := range tests {
+		if got := shiftPoint(tt.s, tt.n); got != tt.want {
+			t.Errorf("shiftPoint(%q, %d) = %v, want %v", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func Test_getFields_numberOptionError(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"unknown option", struct {
+			V int `csv:"0,v,,unknown"`
+		}{}},
+		{"percent on int", struct {
+			V int `csv:"0,v,,percent"`
+		}{}},
+		{"invalid precision", struct {
+			V float64 `csv:"0,v,,precision=x"`
+		}{}},
+		{"thousands equal to decimal", struct {
+			V float64 `csv:"0,v,,thousands=,,decimal=,"`
+		}{}},
+		{"thousands equal to default decimal", struct {
+			V float64 `csv:"0,v,,thousands=."`
+		}{}},
+		{"thousands on string", struct {
+			V string `csv:"0,v,,thousands=,"`
+		}{}},
+		{"percent on bool pointer", struct {
+			V *bool `csv:"0,v,,percent"`
+		}{}},
+		{"round without precision", struct {
+			V float64 `csv:"0,v,,round=half_even"`
+		}{}},
+		{"base on string", struct {
+			V string `csv:"0,v,,base=16"`
+		}{}},
+		{"prefix on float", struct {
+			V float64 `csv:"0,v,,prefix"`
+		}{}},
+		{"clamp on bool", struct {
+			V bool `csv:"0,v,,clamp"`
+		}{}},
+		{"clamp on big.Int", struct {
+			V big.Int `csv:"0,v,,clamp"`
+		}{}},
+		{"bits on int", struct {
+			V int `csv:"0,v,,bits=8"`
+		}{}},
+		{"scale on float", struct {
+			V float64 `csv:"0,v,,scale=2"`
+		}{}},
+		{"round with scale on float", struct {
+			V float64 `csv:"0,v,,scale=2,round=floor"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := getFields(reflect.TypeOf(tt.v)); err == nil {
+				t.Errorf("getFields() error = nil, want error")
+			}
+		})
+	}
+}
+
+type TestIntBaseData struct {
+	ID    uint32 `csv:"0,id,,base=16,prefix"`
+	Mask  uint8  `csv:"1,mask,,base=2"`
+	Mode  int    `csv:"2,mode,,base=8,prefix"`
+	Delta int64  `csv:"3,delta,,base=16,prefix"`
+	Count int    `csv:"4,count,,underscores"`
+}
+
+func TestIntSyntax_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    TestIntBaseData
+		encoded string
+		wantErr bool
+	}{
+		{
+			name:    "underscores without option",
+			raw:     "0xDEAD_BEEF,0b1010,0o755,-0x10,1_000_000\n",
+			wantErr: true,
+		},
+		{
+			name:    "without prefix",
+			raw:     "DEADBEEF,1010,755,-10,1000000\n",
+			want:    TestIntBaseData{0xdeadbeef, 10, 0755, -16, 1000000},
+			encoded: "0xdeadbeef,1010,0o755,-0x10,1000000\n",
+		},
+		{
+			name:    "mixed prefix and underscores",
+			raw:     "0Xff,0b11,0o17,+0x1f,1_000\n",
+			want:    TestIntBaseData{0xff, 3, 017, 0x1f, 1000},
+			encoded: "0xff,11,0o17,0x1f,1000\n",
+		},
+		{
+			name:    "invalid underscores",
+			raw:     "ff,1,1,1,1__000\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(tt.raw)), false)
+			var got TestIntBaseData
+			if err := d.Decode(&got); (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+
+			buf := new(bytes.Buffer)
+			e, _ := NewEncoder(csv.NewWriter(buf), false)
+			if err := e.Encode(&got); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			e.Flush()
+			if buf.String() != tt.encoded {
+				t.Errorf("Encode() = %v, want %v", buf.String(), tt.encoded)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package csve
+
+import (
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Result is a value decoded by ParallelDecoder.
+type Result struct {
+	// Value is the pointer returned by newValue of NewParallelDecoder.
+	Value interface{}
+
+	// Line is the line number of the record.
+	Line int
+
+	// Err is the error of reading or decoding the record. Decoding errors do
+	// not stop ParallelDecoder, while reading errors do.
+	Err error
+}
+
+// ParallelDecoder reads records on one goroutine and decodes them on
+// multiple goroutines. It uses settings of the Decoder, like Location and
+// CustomDecoder, which must be safe for concurrent use.
+//
+// Workers decode on copies of the Decoder, so Records, Skipped and
+// OnProgress of the Decoder are not maintained. Count Result.Err instead.
+// DecodeInto interning is not used.
+//
+//	pd := csve.NewParallelDecoder(decoder, func() interface{} { return new(Order) })
+//	for r := range pd.Decode() {
+//		if r.Err != nil {
+//			return r.Err
+//		}
+//		order := r.Value.(*Order)
+//	}
+type ParallelDecoder struct {
+	// Workers is the number of decoding goroutines. Default is GOMAXPROCS.
+	Workers int
+
+	// If Unordered is true, results are sent as soon as decoded instead of
+	// in the order of lines.
+	Unordered bool
+
+	d        *Decoder
+	newValue func() interface{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// parallelBatch is the number of records sent to a worker at once to reduce
+// channel overhead.
+const parallelBatch = 64
+
+type parallelRecord struct {
+	line      int
+	offset    int64
+	cols      []string
+	positions []fieldPos
+	err       error
+}
+
+type parallelJob struct {
+	seq     int
+	records []parallelRecord
+}
+
+type parallelResult struct {
+	seq     int
+	results []Result
+}
+
+// NewParallelDecoder returns a ParallelDecoder which reads records from d.
+// newValue returns a new pointer to a struct for each record.
+func NewParallelDecoder(d *Decoder, newValue func() interface{}) *ParallelDecoder {
+	return &ParallelDecoder{
+		d:        d,
+		newValue: newValue,
+		done:     make(chan struct{}),
+	}
+}
+
+// Decode starts decoding and returns the channel of results, which is closed
+// after all records are decoded. Decode must be called once. Call Close to
+// stop before the channel is closed.
+func (p *ParallelDecoder) Decode() <-chan Result {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	out := make(chan Result, parallelBatch)
+
+	plan, err := newValuePlan(p.newValue)
+	if err != nil {
+		out <- Result{Err: err}
+		close(out)
+		return out
+	}
+
+	jobs := make(chan parallelJob, workers)
+	decoded := make(chan parallelResult, workers)
+	// window limits batches in flight, so a slow batch does not make
+	// ordered results pile up
+	window := make(chan struct{}, workers*4)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		// each worker has own copy of the decoder for line numbers
+		d := *p.d
+		d.CsvReader, d.cols, d.borrowed = nil, nil, false
+		d.interning, d.interned = false, nil
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(&d, plan, jobs, decoded)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(decoded)
+	}()
+	go p.read(jobs, window)
+	go collectResults(decoded, out, window, p.done, p.Unordered)
+	return out
+}
+
+// Close stops decoding. Results not received yet are discarded.
+func (p *ParallelDecoder) Close() {
+	p.once.Do(func() { close(p.done) })
+}
+
+func (p *ParallelDecoder) read(jobs chan<- parallelJob, window chan struct{}) {
+	defer close(jobs)
+	for seq := 0; ; seq++ {
+		select {
+		case window <- struct{}{}:
+		case <-p.done:
+			return
+		}
+
+		job := parallelJob{seq: seq, records: make([]parallelRecord, 0, parallelBatch)}
+		var err error
+		for len(job.records) < parallelBatch {
+			var cols []string
+			cols, err = p.d.readRecord()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				job.records = append(job.records, parallelRecord{line: p.d.line, offset: p.d.offset, err: err})
+				break
+			}
+			if p.d.borrowed {
+				// cells are reused by the reader
+				cols = cloneStrings(cols)
+			}
+			job.records = append(job.records, parallelRecord{
+				line:      p.d.line,
+				offset:    p.d.offset,
+				cols:      cols,
+				positions: p.cellPositions(cols),
+			})
+		}
+
+		if len(job.records) > 0 {
+			select {
+			case jobs <- job:
+			case <-p.done:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// cellPositions returns positions of cells of the record just read, which
+// workers report in DecodeError since they cannot ask the CsvReader. It
+// returns nil if the CsvReader does not tell positions.
+func (p *ParallelDecoder) cellPositions(cols []string) []fieldPos {
+	if _, ok := p.d.CsvReader.(CsvPositionReader); !ok {
+		return nil
+	}
+	positions := make([]fieldPos, len(cols))
+	for i := range cols {
+		line, column, offset := p.d.cellPos(i, cols)
+		positions[i] = fieldPos{line: line, column: column, offset: offset}
+	}
+	return positions
+}
+
+// newValuePlan returns plan of the type newValue returns.
+func newValuePlan(newValue func() interface{}) (*plan, error) {
+	rv := reflect.ValueOf(newValue())
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, errors.New("invalid value type")
+	}
+	return getPlan(rv.Type())
+}
+
+func cloneStrings(s []string) []string {
+	c := make([]string, len(s))
+	for i := range s {
+		c[i] = strings.Clone(s[i])
+	}
+	return c
+}
+
+func (p *ParallelDecoder) work(d *Decoder, plan *plan, jobs <-chan parallelJob, decoded chan<- parallelResult) {
+	for job := range jobs {
+		r := parallelResult{seq: job.seq, results: make([]Result, len(job.records))}
+		for i, rec := range job.records {
+			r.results[i] = Result{Line: rec.line, Err: rec.err}
+			if rec.err == nil {
+				r.results[i].Value = p.newValue()
+				d.line, d.offset, d.positions = rec.line, rec.offset, rec.positions
+				r.results[i].Err = d.decodeRecord(plan, r.results[i].Value, rec.cols)
+			}
+		}
+
+		select {
+		case decoded <- r:
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// collectResults sends decoded results to out in the order of seq, or as
+// soon as received if unordered. A slot of window is released for each
+// batch sent.
+func collectResults(decoded <-chan parallelResult, out chan<- Result, window <-chan struct{}, done <-chan struct{}, unordered bool) {
+	defer close(out)
+
+	emit := func(results []Result) bool {
+		for _, r := range results {
+			select {
+			case out <- r:
+			case <-done:
+				return false
+			}
+		}
+		<-window
+		return true
+	}
+
+	pending := map[int][]Result{}
+	next := 0
+	for r := range decoded {
+		if unordered {
+			if !emit(r.results) {
+				return
+			}
+			continue
+		}
+
+		pending[r.seq] = r.results
+		for {
+			results, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if !emit(results) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package csve
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func testParallelCsv(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i == 10 {
+			b.WriteString("x,bad\n")
+			continue
+		}
+		fmt.Fprintf(&b, "%d,name%d\n", i, i)
+	}
+	return b.String()
+}
+
+func TestParallelDecoder(t *testing.T) {
+	raw := testParallelCsv(1000)
+	tests := []struct {
+		name      string
+		reader    func() CsvReader
+		unordered bool
+	}{
+		{"ordered", func() CsvReader { return csv.NewReader(strings.NewReader(raw)) }, false},
+		{"unordered", func() CsvReader { return csv.NewReader(strings.NewReader(raw)) }, true},
+		{"tokenizer", func() CsvReader { return NewTokenizer(strings.NewReader(raw)) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := NewDecoder(tt.reader(), false)
+			pd := NewParallelDecoder(d, func() interface{} { return new(testRecord) })
+			pd.Workers = 4
+			pd.Unordered = tt.unordered
+
+			var lines []int
+			for r := range pd.Decode() {
+				lines = append(lines, r.Line)
+				if r.Line == 11 {
+					if r.Err == nil || !strings.Contains(r.Err.Error(), "line:11") {
+						t.Errorf("Result.Err = %v, want error at line 11", r.Err)
+					}
+					continue
+				}
+				if r.Err != nil {
+					t.Fatalf("Result.Err = %v", r.Err)
+				}
+				v := r.Value.(*testRecord)
+				if v.ID != r.Line-1 || v.Name != fmt.Sprintf("name%d", r.Line-1) {
+					t.Errorf("Result.Value = %+v, want line %d", v, r.Line)
+				}
+			}
+
+			if len(lines) != 1000 {
+				t.Fatalf("got %d results, want 1000", len(lines))
+			}
+			if !tt.unordered && !sort.IntsAreSorted(lines) {
+				t.Errorf("results are not in line order")
+			}
+		})
+	}
+}
+
+func TestParallelDecoder_position(t *testing.T) {
+	type data struct {
+		Name string `csv:"0,name"`
+		Qty  int    `csv:"1,qty"`
+	}
+	const raw = "a,1\n\"b\nc\",x\nd,2\n e ,\"3\n\"\nf,y\n"
+	for name, reader := range testReaders(raw) {
+		t.Run(name, func(t *testing.T) {
+			var want []DecodeError
+			d, _ := NewDecoder(reader(), false)
+			for {
+				var v data
+				err := d.Decode(&v)
+				if err == io.EOF {
+					break
+				}
+				var derr *DecodeError
+				if errors.As(err, &derr) {
+					want = append(want, *derr)
+				}
+			}
+
+			var got []DecodeError
+			d, _ = NewDecoder(reader(), false)
+			pd := NewParallelDecoder(d, func() interface{} { return new(data) })
+			pd.Workers = 2
+			for r := range pd.Decode() {
+				var derr *DecodeError
+				if errors.As(r.Err, &derr) {
+					got = append(got, *derr)
+				}
+			}
+
+			if len(got) != 3 || len(got) != len(want) {
+				t.Fatalf("got %d errors, want %d errors of sequential Decode", len(got), len(want))
+			}
+			for i := range got {
+				g, w := got[i], want[i]
+				if g.Line != w.Line || g.Column != w.Column || g.Offset != w.Offset || g.Field != w.Field {
+					t.Errorf("DecodeError = %+v, want %+v", g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestParallelDecoder_readError(t *testing.T) {
+	d, _ := NewDecoder(csv.NewReader(strings.NewReader("1,a\n2,\"b\n")), false)
+	pd := NewParallelDecoder(d, func() interface{} { return new(testRecord) })
+
+	var results []Result
+	for r := range pd.Decode() {
+		results = append(results, r)
+	}
+	var perr *csv.ParseError
+	if len(results) != 2 || results[0].Err != nil || !errors.As(results[1].Err, &perr) {
+		t.Errorf("Decode() = %+v, want a value and a parse error", results)
+	}
+}
+
+func TestParallelDecoder_Close(t *testing.T) {
+	d, _ := NewDecoder(csv.NewReader(strings.NewReader(testParallelCsv(1000))), false)
+	pd := NewParallelDecoder(d, func() interface{} { return new(testRecord) })
+	results := pd.Decode()
+	<-results
+	pd.Close()
+	for range results {
+	}
+}
+
+func TestParallelDecoder_invalidValue(t *testing.T) {
+	d, _ := NewDecoder(csv.NewReader(strings.NewReader("1,a\n")), false)
+	pd := NewParallelDecoder(d, func() interface{} { return testRecord{} })
+	r := <-pd.Decode()
+	if r.Err == nil {
+		t.Errorf("Result.Err = nil, want error")
+	}
+}
@@ -0,0 +1,246 @@
+package csve
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+var planCache sync.Map
+
+// planDecoder decodes raw into the field at p.
+type planDecoder func(d *Decoder, p unsafe.Pointer, raw, format string) error
+
+// planEncoder encodes the field at p.
+type planEncoder func(e *Encoder, p unsafe.Pointer, format string) (string, error)
+
+// plan is a compiled decoding and encoding plan of a struct type.
+// ops[i] accesses fields[i] by its offset from the struct pointer without
+// reflect.Value. A field with tag options, a pointer or a type other than
+// basic kinds and time.Time has no op and goes through fieldDecoder and
+// fieldEncoder.
+type plan struct {
+	fields   []field
+	ops      []fieldOp
+	required bool // some field has the required option
+}
+
+type fieldOp struct {
+	offset uintptr
+	dec    planDecoder
+	enc    planEncoder
+}
+
+func getPlan(t reflect.Type) (*plan, error) {
+	if t.Kind() == reflect.Ptr {
+		return getPlan(t.Elem())
+	}
+
+	if p, ok := planCache.Load(t); ok {
+		return p.(*plan), nil
+	}
+
+	fields, err := getFields(t)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &plan{fields: fields, ops: make([]fieldOp, len(fields))}
+	for i, f := range fields {
+		p.required = p.required || f.csvopts.required
+		offset, ok := fieldOffset(t, f.fieldindex)
+		if !ok || f.csvopts != (tagOptions{}) {
+			continue
+		}
+		p.ops[i].offset = offset
+		p.ops[i].dec, p.ops[i].enc = getPlanEncoder(f.typ)
+	}
+
+	planCache.Store(t, p)
+	return p, nil
+}
+
+// fieldOffset returns offset of the field from the beginning of t.
+// ok is false if the field is promoted through an embedded pointer.
+func fieldOffset(t reflect.Type, index []int) (offset uintptr, ok bool) {
+	for _, x := range index {
+		if t.Kind() != reflect.Struct {
+			return 0, false
+		}
+		f := t.Field(x)
+		offset += f.Offset
+		t = f.Type
+	}
+	return offset, true
+}
+
+func getPlanEncoder(t reflect.Type) (planDecoder, planEncoder) {
+	if isSQLNullType(t) || isSQLType(t) {
+		return nil, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int:
+		return intPlan[int](t)
+	case reflect.Int8:
+		return intPlan[int8](t)
+	case reflect.Int16:
+		return intPlan[int16](t)
+	case reflect.Int32:
+		return intPlan[int32](t)
+	case reflect.Int64:
+		return intPlan[int64](t)
+	case reflect.Uint:
+		return uintPlan[uint](t)
+	case reflect.Uint8:
+		return uintPlan[uint8](t)
+	case reflect.Uint16:
+		return uintPlan[uint16](t)
+	case reflect.Uint32:
+		return uintPlan[uint32](t)
+	case reflect.Uint64:
+		return uintPlan[uint64](t)
+	case reflect.Float32:
+		return floatPlan[float32](t)
+	case reflect.Float64:
+		return floatPlan[float64](t)
+	case reflect.Bool:
+		return boolPlanDecoder, boolPlanEncoder
+	case reflect.String:
+		return stringPlanDecoder, stringPlanEncoder
+	}
+	if t == timeType {
+		return timePlanDecoder, timePlanEncoder
+	}
+	return nil, nil
+}
+
+func intPlan[T int | int8 | int16 | int32 | int64](t reflect.Type) (planDecoder, planEncoder) {
+	dec := func(d *Decoder, p unsafe.Pointer, raw, format string) error {
+		return decodeInt((*T)(p), raw, t)
+	}
+	enc := func(e *Encoder, p unsafe.Pointer, format string) (string, error) {
+		return strconv.FormatInt(int64(*(*T)(p)), 10), nil
+	}
+	return dec, enc
+}
+
+func uintPlan[T uint | uint8 | uint16 | uint32 | uint64](t reflect.Type) (planDecoder, planEncoder) {
+	dec := func(d *Decoder, p unsafe.Pointer, raw, format string) error {
+		return decodeUint((*T)(p), raw, t)
+	}
+	enc := func(e *Encoder, p unsafe.Pointer, format string) (string, error) {
+		return strconv.FormatUint(uint64(*(*T)(p)), 10), nil
+	}
+	return dec, enc
+}
+
+func floatPlan[T float32 | float64](t reflect.Type) (planDecoder, planEncoder) {
+	bits := t.Bits()
+	dec := func(d *Decoder, p unsafe.Pointer, raw, format string) error {
+		return decodeFloat((*T)(p), raw, t)
+	}
+	enc := func(e *Encoder, p unsafe.Pointer, format string) (string, error) {
+		return strconv.FormatFloat(float64(*(*T)(p)), 'f', -1, bits), nil
+	}
+	return dec, enc
+}
+
+// decodeInt parses raw into p. t is the field type reported in RangeError,
+// or nil for T.
+func decodeInt[T ~int | ~int8 | ~int16 | ~int32 | ~int64](p *T, raw string, t reflect.Type) error {
+	n, err := strconv.ParseInt(raw, 10, int(unsafe.Sizeof(*p))*8)
+	if err != nil {
+		if isRangeError(err) {
+			return rangeError[T](raw, t)
+		}
+		return err
+	}
+	*p = T(n)
+	return nil
+}
+
+func decodeUint[T ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64](p *T, raw string, t reflect.Type) error {
+	n, err := strconv.ParseUint(raw, 10, int(unsafe.Sizeof(*p))*8)
+	if err != nil {
+		if isRangeError(err) {
+			return rangeError[T](raw, t)
+		}
+		if _, ierr := strconv.ParseInt(raw, 10, 64); ierr == nil || isRangeError(ierr) {
+			// negative value
+			return rangeError[T](raw, t)
+		}
+		return err
+	}
+	*p = T(n)
+	return nil
+}
+
+func decodeFloat[T ~float32 | ~float64](p *T, raw string, t reflect.Type) error {
+	n, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		if isRangeError(err) {
+			return rangeError[T](raw, t)
+		}
+		return err
+	}
+	// same as reflect.Value.OverflowFloat
+	if x := math.Abs(n); unsafe.Sizeof(*p) == 4 && math.MaxFloat32 < x && x <= math.MaxFloat64 {
+		return rangeError[T](raw, t)
+	}
+	*p = T(n)
+	return nil
+}
+
+func rangeError[T any](raw string, t reflect.Type) error {
+	if t == nil {
+		t = reflect.TypeFor[T]()
+	}
+	return &RangeError{Raw: raw, Type: t}
+}
+
+func decodeTime(d *Decoder, p *time.Time, raw, format string) error {
+	var t time.Time
+	if raw != "" {
+		var err error
+		t, err = time.ParseInLocation(format, raw, d.Location)
+		if err != nil {
+			return err
+		}
+	}
+	*p = t
+	return nil
+}
+
+func boolPlanDecoder(d *Decoder, p unsafe.Pointer, raw, format string) error {
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return err
+	}
+	*(*bool)(p) = b
+	return nil
+}
+
+func boolPlanEncoder(e *Encoder, p unsafe.Pointer, format string) (string, error) {
+	return strconv.FormatBool(*(*bool)(p)), nil
+}
+
+func stringPlanDecoder(d *Decoder, p unsafe.Pointer, raw, format string) error {
+	*(*string)(p) = raw
+	return nil
+}
+
+func stringPlanEncoder(e *Encoder, p unsafe.Pointer, format string) (string, error) {
+	return *(*string)(p), nil
+}
+
+func timePlanDecoder(d *Decoder, p unsafe.Pointer, raw, format string) error {
+	return decodeTime(d, (*time.Time)(p), raw, format)
+}
+
+func timePlanEncoder(e *Encoder, p unsafe.Pointer, format string) (string, error) {
+	return (*time.Time)(p).In(e.Location).Format(format), nil
+}
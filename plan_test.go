@@ -0,0 +1,86 @@
+package csve
+
+import (
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testPlanStatus int8
+
+type testPlanData struct {
+	Int8    int8           `csv:"0,int8"`
+	Uint16  uint16         `csv:"1,uint16"`
+	Float32 float32        `csv:"2,float32"`
+	Bool    bool           `csv:"3,bool"`
+	Status  testPlanStatus `csv:"4,status"`
+	Time    time.Time      `csv:"5,time,2006-01-02"`
+	Ptr     *int           `csv:"6,ptr"`
+	Qty     int            `csv:"7,qty,,thousands=,"`
+}
+
+func TestPlan(t *testing.T) {
+	p, err := getPlan(reflect.TypeOf(testPlanData{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiled := make([]bool, len(p.ops))
+	for i, op := range p.ops {
+		compiled[i] = op.dec != nil
+	}
+	// pointer and options go through the reflect path
+	want := []bool{true, true, true, true, true, true, false, false}
+	if !reflect.DeepEqual(compiled, want) {
+		t.Errorf("getPlan() compiled = %v, want %v", compiled, want)
+	}
+}
+
+func TestPlan_codec(t *testing.T) {
+	raw := "-128,65535,1.5,true,3,2017-12-24,7,\"1,234\"\n"
+	d, _ := NewDecoder(csv.NewReader(strings.NewReader(raw)), false)
+	var v testPlanData
+	if err := d.Decode(&v); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if v.Int8 != -128 || v.Uint16 != 65535 || v.Float32 != 1.5 || !v.Bool || v.Status != 3 ||
+		!v.Time.Equal(time.Date(2017, 12, 24, 0, 0, 0, 0, time.UTC)) || *v.Ptr != 7 ||
+		v.Qty != 1234 {
+		t.Errorf("Decode() = %+v", v)
+	}
+
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	e, _ := NewEncoder(w, false)
+	if err := e.Encode(&v); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	w.Flush()
+	if b.String() != raw {
+		t.Errorf("Encode() = %q, want %q", b.String(), raw)
+	}
+}
+
+func TestPlan_rangeError(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"int8", "128,0,0,true,0,2017-12-24,,0"},
+		{"negative uint", "0,-1,0,true,0,2017-12-24,,0"},
+		{"float32", "0,0,1e39,true,0,2017-12-24,,0"},
+		{"named int8", "0,0,0,true,-129,2017-12-24,,0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(tt.raw)), false)
+			var v testPlanData
+			var rerr *RangeError
+			if err := d.Decode(&v); !errors.As(err, &rerr) {
+				t.Errorf("Decode() error = %v, want RangeError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package csve
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// sniffSize is the number of bytes Sniff inspects.
+const sniffSize = 64 * 1024
+
+// sniffDelimiters are delimiter candidates in order of preference.
+var sniffDelimiters = []rune{',', '\t', ';', '|'}
+
+// Sniff inspects a sample of r and guesses its Dialect, which is delimiter
+// among , ; \t and |, quote character among " and ', quoting, header presence,
+// line terminator and charset.
+// Since Sniff reads the sample from r, use the returned reader to read csv
+// from the beginning.
+//
+//	dialect, r, err := csve.Sniff(upload)
+//	if err != nil {
+//		return err
+//	}
+//	decoder, err := csve.NewDecoderFromReader(r, dialect)
+func Sniff(r io.Reader) (Dialect, io.Reader, error) {
+	sample := make([]byte, sniffSize)
+	n, err := io.ReadFull(r, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Dialect{}, nil, err
+	}
+	sample = sample[:n]
+	truncated := err == nil
+
+	return SniffBytes(sample, truncated), io.MultiReader(bytes.NewReader(sample), r), nil
+}
+
+// SniffBytes guesses Dialect from sample. If truncated is true, the last
+// line of sample is ignored since it may be incomplete.
+func SniffBytes(sample []byte, truncated bool) Dialect {
+	var dl Dialect
+	dl.Charset, dl.BOM = sniffCharset(sample)
+
+	text, err := sniffText(sample, dl.Charset)
+	if err != nil {
+		return dl
+	}
+	quote := sniffQuote(text)
+	if quote != '"' {
+		dl.Quote = quote
+	}
+	records := splitRecords(text, quote, truncated)
+	if len(records) == 0 {
+		return dl
+	}
+
+	dl.Comma = sniffDelimiter(records, quote)
+	dl.UseCRLF = strings.Contains(text, "\r\n")
+
+	rows, err := sniffRows(records, dl)
+	if err != nil {
+		dl.LazyQuotes = true
+		rows, _ = sniffRows(records, dl)
+	}
+	for _, row := range rows {
+		if len(row) != len(rows[0]) {
+			dl.FieldsPerRecord = -1
+			break
+		}
+	}
+	dl.Header = sniffHeader(rows)
+	return dl
+}
+
+// sniffCharset guesses charset from byte order mark or byte patterns.
+func sniffCharset(sample []byte) (charset Charset, bom bool) {
+	switch {
+	case bytes.HasPrefix(sample, utf8BOM):
+		return UTF8, true
+	case bytes.HasPrefix(sample, utf16LEBOM):
+		return UTF16LE, true
+	case bytes.HasPrefix(sample, utf16BEBOM):
+		return UTF16BE, true
+	}
+
+	// ASCII text in UTF-16 has zero at every other byte
+	var even, odd int
+	for i, b := range sample {
+		if b == 0 {
+			if i%2 == 0 {
+				even++
+			} else {
+				odd++
+			}
+		}
+	}
+	if half := len(sample) / 4; half > 0 {
+		if odd > half && even == 0 {
+			return UTF16LE, false
+		}
+		if even > half && odd == 0 {
+			return UTF16BE, false
+		}
+	}
+
+	if validUTF8(sample) {
+		return UTF8, false
+	}
+	if sniffScore(sample, EUCJP) < sniffScore(sample, ShiftJIS) {
+		return EUCJP, false
+	}
+	return ShiftJIS, false
+}
+
+// validUTF8 is like utf8.Valid but allows a rune truncated at the end.
+func validUTF8(b []byte) bool {
+	for i := 0; i < utf8.UTFMax && len(b) > 0; i++ {
+		if utf8.Valid(b) {
+			return true
+		}
+		b = b[:len(b)-1]
+	}
+	return len(b) == 0
+}
+
+// sniffScore returns how unlikely sample is written in charset. Invalid
+// sequences and half-width kana, which rarely appear in EUC-JP text decoded
+// as Shift_JIS, are counted.
+func sniffScore(sample []byte, charset Charset) int {
+	var dec []byte
+	switch charset {
+	case ShiftJIS:
+		dec, _ = japanese.ShiftJIS.NewDecoder().Bytes(sample)
+	case EUCJP:
+		dec, _ = japanese.EUCJP.NewDecoder().Bytes(sample)
+	}
+	score := 0
+	for _, r := range string(dec) {
+		switch {
+		case r == utf8.RuneError:
+			score += 10
+		case r >= 0xFF61 && r <= 0xFF9F:
+			score++
+		}
+	}
+	return score
+}
+
+func sniffText(sample []byte, charset Charset) (string, error) {
+	for _, bom := range [][]byte{utf8BOM, utf16LEBOM, utf16BEBOM} {
+		if bytes.HasPrefix(sample, bom) {
+			sample = sample[len(bom):]
+			break
+		}
+	}
+	enc := charset.encoding()
+	if enc == nil {
+		return string(sample), nil
+	}
+	text, err := enc.NewDecoder().Bytes(sample)
+	return string(text), err
+}
+
+// sniffQuoteChars are quote candidates in order of preference.
+var sniffQuoteChars = []byte{'"', '\''}
+
+// sniffQuote chooses the quote which encloses the most fields. A quote
+// encloses a field if it follows the beginning of a line or a delimiter
+// candidate, and another one precedes a delimiter candidate or the end of a
+// line. So an apostrophe in a word like O'Brien does not count.
+func sniffQuote(text string) rune {
+	best, bestScore := sniffQuoteChars[0], 0
+	for _, q := range sniffQuoteChars {
+		opens, closes := 0, 0
+		for i := 0; i < len(text); i++ {
+			if text[i] != q {
+				continue
+			}
+			if i == 0 || isSniffBoundary(text[i-1]) {
+				opens++
+			}
+			if i == len(text)-1 || isSniffBoundary(text[i+1]) {
+				closes++
+			}
+		}
+		score := opens
+		if closes < score {
+			score = closes
+		}
+		if score > bestScore {
+			best, bestScore = q, score
+		}
+	}
+	return rune(best)
+}
+
+// isSniffBoundary reports whether c is a delimiter candidate or a line
+// terminator.
+func isSniffBoundary(c byte) bool {
+	if c == '\r' || c == '\n' {
+		return true
+	}
+	for _, delim := range sniffDelimiters {
+		if rune(c) == delim {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffRows reads records with dl. The number of fields may vary.
+func sniffRows(records []string, dl Dialect) ([][]string, error) {
+	dl.FieldsPerRecord = -1
+	r := dl.newCsvReader(strings.NewReader(strings.Join(records, "\n")))
+	var rows [][]string
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+// splitRecords splits text into records. A newline in quotes does not end
+// a record.
+func splitRecords(text string, quote rune, truncated bool) []string {
+	var records []string
+	inQuote := false
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch rune(text[i]) {
+		case quote:
+			inQuote = !inQuote
+		case '\n':
+			if !inQuote {
+				records = append(records, strings.TrimSuffix(text[start:i], "\r"))
+				start = i + 1
+			}
+		}
+	}
+	if start < len(text) && !truncated {
+		records = append(records, text[start:])
+	}
+	return records
+}
+
+// countOutsideQuotes counts delim in record which is not quoted.
+func countOutsideQuotes(record string, delim, quote rune) int {
+	n := 0
+	inQuote := false
+	for _, r := range record {
+		switch {
+		case r == quote:
+			inQuote = !inQuote
+		case r == delim && !inQuote:
+			n++
+		}
+	}
+	return n
+}
+
+// sniffDelimiter chooses the delimiter which appears the same number of times
+// in most records.
+func sniffDelimiter(records []string, quote rune) rune {
+	best, bestScore := sniffDelimiters[0], 0
+	for _, delim := range sniffDelimiters {
+		freq := map[int]int{}
+		for _, record := range records {
+			freq[countOutsideQuotes(record, delim, quote)]++
+		}
+		// score is the number of records sharing the most common non-zero count
+		score := 0
+		for count, n := range freq {
+			if count > 0 && n > score {
+				score = n
+			}
+		}
+		if score > bestScore {
+			best, bestScore = delim, score
+		}
+	}
+	return best
+}
+
+// sniffHeader guesses if the first row is a header. A column votes for a
+// header if its cells below the first row are all numbers while the first
+// one is not, or all have the same length different from the first one.
+func sniffHeader(rows [][]string) bool {
+	if len(rows) < 2 {
+		return false
+	}
+	header, data := rows[0], rows[1:]
+
+	votes := 0
+	for i, name := range header {
+		numeric, length := true, -1
+		for _, row := range data {
+			if i >= len(row) {
+				numeric, length = false, -2
+				break
+			}
+			if !isNumber(row[i]) {
+				numeric = false
+			}
+			if length == -1 {
+				length = utf8.RuneCountInString(row[i])
+			} else if length != utf8.RuneCountInString(row[i]) {
+				length = -2
+			}
+		}
+
+		switch {
+		case numeric && !isNumber(name):
+			votes++
+		case numeric:
+			votes--
+		case length >= 0 && length != utf8.RuneCountInString(name):
+			votes++
+		case length >= 0:
+			votes--
+		}
+	}
+	return votes > 0
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return err == nil
+}
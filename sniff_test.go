@@ -0,0 +1,99 @@
+package csve
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestSniffBytes(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample string
+		want   Dialect
+	}{
+		{
+			name:   "csv with header",
+			sample: "id,name,price\n1,apple,1.5\n2,banana,0.25\n3,cherry,10\n",
+			want:   Dialect{Comma: ',', Header: true},
+		},
+		{
+			name:   "csv without header",
+			sample: "1,apple,1.5\n2,banana,0.25\n3,cherry,10\n",
+			want:   Dialect{Comma: ','},
+		},
+		{
+			name:   "tsv with quoted delimiter",
+			sample: "code\tname\nJP\t\"Japan\tNippon\"\nUS\t\"United States\"\r\nFR\tFrance\r\n",
+			want:   Dialect{Comma: '\t', Header: true, UseCRLF: true},
+		},
+		{
+			name:   "semicolon with decimal comma",
+			sample: "name;amount\nfoo;\"1,5\"\nbar;\"2,25\"\n",
+			want:   Dialect{Comma: ';', Header: true},
+		},
+		{
+			name:   "pipe with bare quotes",
+			sample: "1|5\" disk|x\n2|3\" disk|y\n",
+			want:   Dialect{Comma: '|', LazyQuotes: true},
+		},
+		{
+			name:   "single quotes",
+			sample: "'a;b';c\n'd;e';f\n'g';h\n",
+			want:   Dialect{Comma: ';', Quote: '\''},
+		},
+		{
+			name:   "apostrophes in words",
+			sample: "1,O'Brien,\"it's, fine\"\n2,D'Arcy,ok\n3,O'Neil,\"yes, sir\"\n",
+			want:   Dialect{Comma: ','},
+		},
+		{
+			name:   "utf-8 bom",
+			sample: "\xEF\xBB\xBFa,b\n1,2\n",
+			want:   Dialect{Comma: ',', BOM: true, Header: true},
+		},
+		{
+			name:   "shift_jis",
+			sample: mustEncode(t, japanese.ShiftJIS.NewEncoder(), "名前,年齢\n鈴木一郎,28\nｽｽﾞｷ,30\n"),
+			want:   Dialect{Comma: ',', Charset: ShiftJIS, Header: true},
+		},
+		{
+			name:   "euc-jp",
+			sample: mustEncode(t, japanese.EUCJP.NewEncoder(), "名前,年齢\n鈴木一郎,28\n山田花子,30\n"),
+			want:   Dialect{Comma: ',', Charset: EUCJP, Header: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffBytes([]byte(tt.sample), false); got != tt.want {
+				t.Errorf("SniffBytes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniff(t *testing.T) {
+	const text = "id;name\n1;foo\n2;bar\n"
+	dialect, r, err := Sniff(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Sniff() error = %v", err)
+	}
+	if rest, _ := io.ReadAll(r); string(rest) != text {
+		t.Fatalf("Sniff() reader = %q, want %q", rest, text)
+	}
+
+	_, r, _ = Sniff(strings.NewReader(text))
+	d, err := NewDecoderFromReader(r, dialect)
+	if err != nil {
+		t.Fatalf("NewDecoderFromReader() error = %v", err)
+	}
+	var v testCharsetData
+	if err := d.Decode(&v); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if v.ID != 1 || v.Name != "foo" {
+		t.Errorf("Decode() = %v, want {1 foo}", v)
+	}
+}
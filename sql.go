@@ -0,0 +1,107 @@
+package csve
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// isSQLNullType reports whether t is one of sql.NullString, sql.NullInt64,
+// sql.NullTime, sql.Null[T] and so on.
+func isSQLNullType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == "database/sql" &&
+		strings.HasPrefix(t.Name(), "Null") && t.NumField() == 2 &&
+		t.Field(1).Name == "Valid" && t.Field(1).Type.Kind() == reflect.Bool
+}
+
+// isSQLType reports whether t implements sql.Scanner or driver.Valuer.
+func isSQLType(t reflect.Type) bool {
+	return t.Kind() != reflect.Ptr &&
+		(reflect.PtrTo(t).Implements(scannerType) || reflect.PtrTo(t).Implements(valuerType))
+}
+
+// getSQLNullFieldEncoder decodes the value of sql.Null* type with the field
+// decoder of its inner type, so time format and tag options are respected.
+func getSQLNullFieldEncoder(t reflect.Type, opts *tagOptions) (dec fieldDecoder, enc fieldEncoder, err error) {
+	rdec, renc, err := getFieldEncoder(t.Field(0).Type, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dec = func(d *Decoder, v reflect.Value, raw, format string) error {
+		if d.isNull(raw, opts) {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if err := rdec(d, v.Field(0), raw, format); err != nil {
+			return err
+		}
+		v.Field(1).SetBool(true)
+		return nil
+	}
+	enc = func(e *Encoder, v reflect.Value, format string) (string, error) {
+		if !v.Field(1).Bool() {
+			return e.nullValue(opts), nil
+		}
+		return renc(e, v.Field(0), format)
+	}
+	return
+}
+
+// getSQLFieldEncoder decodes value with sql.Scanner and encodes value with
+// driver.Valuer.
+func getSQLFieldEncoder(t reflect.Type, opts *tagOptions) (dec fieldDecoder, enc fieldEncoder, err error) {
+	dec = func(d *Decoder, v reflect.Value, raw, format string) error {
+		s, ok := v.Addr().Interface().(sql.Scanner)
+		if !ok {
+			return fmt.Errorf("%s does not implement sql.Scanner", v.Type())
+		}
+		if d.isNull(raw, opts) {
+			return s.Scan(nil)
+		}
+		return s.Scan(raw)
+	}
+	enc = func(e *Encoder, v reflect.Value, format string) (string, error) {
+		valuer, ok := addr(v).Interface().(driver.Valuer)
+		if !ok {
+			return "", fmt.Errorf("%s does not implement driver.Valuer", v.Type())
+		}
+		val, err := valuer.Value()
+		if err != nil {
+			return "", err
+		}
+		return e.encodeDriverValue(val, format, opts)
+	}
+	return
+}
+
+// encodeDriverValue encodes driver.Value returned by driver.Valuer.
+func (e *Encoder) encodeDriverValue(val driver.Value, format string, opts *tagOptions) (string, error) {
+	switch x := val.(type) {
+	case nil:
+		return e.nullValue(opts), nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case []byte:
+		return string(x), nil
+	case string:
+		return x, nil
+	case time.Time:
+		return x.In(e.Location).Format(format), nil
+	}
+	return "", errors.New("unsupported driver value")
+}
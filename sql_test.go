@@ -0,0 +1,136 @@
+package csve
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testUpper stores string in upper case through sql.Scanner.
+type testUpper string
+
+func (u *testUpper) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case nil:
+		*u = ""
+	case string:
+		*u = testUpper(strings.ToUpper(s))
+	default:
+		return errors.New("unsupported type")
+	}
+	return nil
+}
+
+func (u testUpper) Value() (driver.Value, error) {
+	if u == "" {
+		return nil, nil
+	}
+	return strings.ToLower(string(u)), nil
+}
+
+type TestSQLData struct {
+	Str   sql.NullString    `csv:"0,str"`
+	Int   sql.NullInt64     `csv:"1,int"`
+	Bool  sql.NullBool      `csv:"2,bool"`
+	Time  sql.NullTime      `csv:"3,time,2006-01-02T15:04:05"`
+	Dec   sql.Null[Decimal] `csv:"4,dec,,null=\\N"`
+	Upper testUpper         `csv:"5,upper"`
+	Float sql.Null[float64] `csv:"6,float,,thousands=,"`
+	Ptr   *sql.Null[int]    `csv:"7,ptr"`
+}
+
+func TestSQL_Decode(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		nullValues []string
+		want       TestSQLData
+	}{
+		{
+			name: "valid case",
+			raw:  `str,1,true,2017-12-24T15:30:00,12.30,abc,"1,234.5",5`,
+			want: TestSQLData{
+				Str:   sql.NullString{String: "str", Valid: true},
+				Int:   sql.NullInt64{Int64: 1, Valid: true},
+				Bool:  sql.NullBool{Bool: true, Valid: true},
+				Time:  sql.NullTime{Time: time.Unix(1514129400, 0).In(time.UTC), Valid: true},
+				Dec:   sql.Null[Decimal]{V: MustParseDecimal("12.30"), Valid: true},
+				Upper: "ABC",
+				Float: sql.Null[float64]{V: 1234.5, Valid: true},
+				Ptr:   &sql.Null[int]{V: 5, Valid: true},
+			},
+		},
+		{
+			name: "null case",
+			raw:  `,,,,\N,,,`,
+			want: TestSQLData{},
+		},
+		{
+			name:       "null values case",
+			raw:        `NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL`,
+			nullValues: []string{"NULL"},
+			want:       TestSQLData{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(tt.raw)), false)
+			d.NullValues = tt.nullValues
+			var got TestSQLData
+			if err := d.Decode(&got); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQL_Encode(t *testing.T) {
+	tests := []struct {
+		name      string
+		v         TestSQLData
+		nullValue string
+		want      string
+	}{
+		{
+			name: "valid case",
+			v: TestSQLData{
+				Str:   sql.NullString{String: "str", Valid: true},
+				Int:   sql.NullInt64{Int64: 1, Valid: true},
+				Bool:  sql.NullBool{Bool: false, Valid: true},
+				Time:  sql.NullTime{Time: time.Unix(1514129400, 0), Valid: true},
+				Dec:   sql.Null[Decimal]{V: MustParseDecimal("12.30"), Valid: true},
+				Upper: "ABC",
+				Float: sql.Null[float64]{V: 1234.5, Valid: true},
+			},
+			want: "str,1,false,2017-12-24T15:30:00,12.30,abc,\"1,234.5\",\n",
+		},
+		{
+			name:      "null case",
+			nullValue: "NULL",
+			want:      "NULL,NULL,NULL,NULL,\\N,NULL,NULL,NULL\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			e, _ := NewEncoder(csv.NewWriter(buf), false)
+			e.NullValue = tt.nullValue
+			if err := e.Encode(tt.v); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			e.Flush()
+			if buf.String() != tt.want {
+				t.Errorf("Encode() = %v, want %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package csve
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"unicode"
+)
+
+// Tokenizer is a fast csv reader which reuses its buffers between records.
+// It implements CsvReader, CsvBytesReader and CsvPositionReader. Decoder
+// reads cells with ReadBytes, so decoding numeric fields does not allocate
+// per record.
+//
+// Tokenizer supports RFC 4180 csv with single byte delimiter and quote.
+// Like encoding/csv, empty lines are skipped, \r\n in a quoted field is read
+// as \n and parse errors are *csv.ParseError with the same Err, such as
+// csv.ErrQuote for a malformed quoted field.
+type Tokenizer struct {
+	// Comma is the field delimiter. Default is ','.
+	Comma byte
+
+	// Quote is the quote character. Default is '"'.
+	Quote byte
+
+	// Comment, if not 0, is the comment character. Lines beginning with it
+	// are skipped.
+	Comment byte
+
+	// LazyQuotes allows a quote to appear in an unquoted field and a
+	// non-doubled quote to appear in a quoted field, like csv.Reader.
+	LazyQuotes bool
+
+	// TrimLeadingSpace ignores leading white space in a field.
+	TrimLeadingSpace bool
+
+	// FieldsPerRecord is the number of expected fields per record like
+	// csv.Reader. Zero means the number of the first record, and negative
+	// means variable number of fields.
+	FieldsPerRecord int
+
+	r         *bufio.Reader
+	line      int
+	offset    int64
+	lineStart int64 // offset of the last line read
+	recStart  int64 // offset of the first line of the last record
+	lineBuf   []byte
+	record    []byte
+	ends      []int
+	starts    []fieldPos
+	fields    [][]byte
+}
+
+// fieldPos is the line, 1-based byte column and input offset where a field
+// begins.
+type fieldPos struct {
+	line, column int
+	offset       int64
+}
+
+// NewTokenizer returns a Tokenizer which reads from r.
+func NewTokenizer(r io.Reader) *Tokenizer {
+	return &Tokenizer{
+		Comma: ',',
+		Quote: '"',
+		r:     bufio.NewReader(r),
+	}
+}
+
+// ReadBytes reads a record as byte slices. The slices are owned by Tokenizer
+// and valid until the next call of Read or ReadBytes.
+// Like csv.Reader, a record with wrong number of fields is returned with
+// *csv.ParseError of csv.ErrFieldCount.
+func (t *Tokenizer) ReadBytes() ([][]byte, error) {
+	if err := t.readRecord(); err != nil {
+		return nil, err
+	}
+
+	t.fields = t.fields[:0]
+	start := 0
+	for _, end := range t.ends {
+		t.fields = append(t.fields, t.record[start:end:end])
+		start = end
+	}
+
+	if t.FieldsPerRecord == 0 {
+		t.FieldsPerRecord = len(t.fields)
+	} else if t.FieldsPerRecord > 0 && len(t.fields) != t.FieldsPerRecord {
+		line := t.starts[0].line
+		return t.fields, &csv.ParseError{StartLine: line, Line: line, Column: 1, Err: csv.ErrFieldCount}
+	}
+	return t.fields, nil
+}
+
+// Read reads a record. Unlike ReadBytes, the returned record is owned by the
+// caller.
+func (t *Tokenizer) Read() ([]string, error) {
+	fields, err := t.ReadBytes()
+	if fields == nil {
+		return nil, err
+	}
+	record := make([]string, len(fields))
+	for i, f := range fields {
+		record[i] = string(f)
+	}
+	return record, err
+}
+
+// FieldPos returns the line and column where the field at index of the last
+// record read begins. The column is a 1-based byte index, and the beginning
+// of a quoted field is its opening quote. It panics if index is out of range.
+func (t *Tokenizer) FieldPos(field int) (line, column int) {
+	p := t.starts[field]
+	return p.line, p.column
+}
+
+// fieldOffset returns the input offset where the field of the last record
+// begins.
+func (t *Tokenizer) fieldOffset(field int) int64 {
+	return t.starts[field].offset
+}
+
+// recordOffset returns the input offset of the first line of the last
+// record read.
+func (t *Tokenizer) recordOffset() int64 {
+	return t.recStart
+}
+
+// InputOffset returns the input stream byte offset of the end of the last
+// record read.
+func (t *Tokenizer) InputOffset() int64 {
+	return t.offset
+}
+
+// readLine reads a line including the trailing \n. \r\n is read as \n, and
+// \r at EOF is dropped like encoding/csv. The line is valid until the next
+// readLine.
+func (t *Tokenizer) readLine() ([]byte, error) {
+	line, err := t.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		t.lineBuf = append(t.lineBuf[:0], line...)
+		for err == bufio.ErrBufferFull {
+			line, err = t.r.ReadSlice('\n')
+			t.lineBuf = append(t.lineBuf, line...)
+		}
+		line = t.lineBuf
+	}
+	if len(line) > 0 && err == io.EOF {
+		err = nil
+		t.line++
+		t.lineStart = t.offset
+		t.offset += int64(len(line))
+		if line[len(line)-1] == '\r' {
+			line = line[:len(line)-1]
+		}
+		return line, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t.line++
+	t.lineStart = t.offset
+	t.offset += int64(len(line))
+	if n := len(line); n >= 2 && line[n-2] == '\r' {
+		line[n-2] = '\n'
+		line = line[:n-1]
+	}
+	return line, nil
+}
+
+// lineEnd returns the length of line without the trailing \n.
+func lineEnd(line []byte) int {
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		return n - 1
+	}
+	return len(line)
+}
+
+func (t *Tokenizer) readRecord() error {
+	comma, quote := t.comma(), t.quote()
+
+	var line []byte
+	for {
+		var err error
+		line, err = t.readLine()
+		if err != nil {
+			return err
+		}
+		if t.Comment != 0 && len(line) > 0 && line[0] == t.Comment {
+			continue
+		}
+		if lineEnd(line) == 0 {
+			continue
+		}
+		break
+	}
+
+	recLine := t.line
+	t.recStart = t.lineStart
+	t.record = t.record[:0]
+	t.ends = t.ends[:0]
+	t.starts = t.starts[:0]
+	pos := 0
+	for {
+		end := lineEnd(line)
+		if t.TrimLeadingSpace {
+			pos = end - len(bytes.TrimLeftFunc(line[pos:end], unicode.IsSpace))
+		}
+		t.starts = append(t.starts, fieldPos{t.line, pos + 1, t.lineStart + int64(pos)})
+
+		if pos < end && line[pos] == quote {
+			pos++
+			for {
+				i := bytes.IndexByte(line[pos:], quote)
+				if i < 0 {
+					// the quoted field continues to the next line
+					t.record = append(t.record, line[pos:]...)
+					column := len(line) + 1
+					var err error
+					if line, err = t.readLine(); err != nil {
+						if err != io.EOF {
+							return err
+						}
+						if !t.LazyQuotes {
+							return t.parseError(recLine, column, csv.ErrQuote)
+						}
+						// the field ends at EOF
+						t.ends = append(t.ends, len(t.record))
+						return nil
+					}
+					pos = 0
+					continue
+				}
+				t.record = append(t.record, line[pos:pos+i]...)
+				pos += i + 1
+				if pos < len(line) && line[pos] == quote {
+					t.record = append(t.record, quote)
+					pos++
+					continue
+				}
+				end = lineEnd(line)
+				if pos == end || line[pos] == comma {
+					break
+				}
+				if !t.LazyQuotes {
+					return t.parseError(recLine, pos, csv.ErrQuote)
+				}
+				// a bare quote in the quoted field
+				t.record = append(t.record, quote)
+			}
+
+			t.ends = append(t.ends, len(t.record))
+			if pos == end {
+				return nil
+			}
+			pos++
+			continue
+		}
+
+		fieldEnd := end
+		i := bytes.IndexByte(line[pos:end], comma)
+		if i >= 0 {
+			fieldEnd = pos + i
+		}
+		if !t.LazyQuotes {
+			if j := bytes.IndexByte(line[pos:fieldEnd], quote); j >= 0 {
+				return t.parseError(recLine, pos+j+1, csv.ErrBareQuote)
+			}
+		}
+		t.record = append(t.record, line[pos:fieldEnd]...)
+		t.ends = append(t.ends, len(t.record))
+		if i < 0 {
+			return nil
+		}
+		pos = fieldEnd + 1
+	}
+}
+
+// parseError returns *csv.ParseError at column of the current line.
+func (t *Tokenizer) parseError(recLine, column int, err error) error {
+	return &csv.ParseError{StartLine: recLine, Line: t.line, Column: column, Err: err}
+}
+
+func (t *Tokenizer) comma() byte {
+	if t.Comma == 0 {
+		return ','
+	}
+	return t.Comma
+}
+
+func (t *Tokenizer) quote() byte {
+	if t.Quote == 0 {
+		return '"'
+	}
+	return t.Quote
+}
@@ -0,0 +1,162 @@
+package csve
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenizer(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   string
+		comma byte
+		quote byte
+	}{
+		{"simple", "a,b,c\n1,2,3\n", 0, 0},
+		{"no trailing newline", "a,b\n1,2", 0, 0},
+		{"empty fields", ",,\na,,b\n", 0, 0},
+		{"quoted", `"a,b","c""d",""` + "\n", 0, 0},
+		{"quoted newline", "\"a\r\nb\",c\r\nd,e\r\n", 0, 0},
+		{"empty lines", "a,b\n\n\r\nc,d\n", 0, 0},
+		{"tsv", "a\tb\n\"c\td\"\te\n", '\t', 0},
+		{"long line", strings.Repeat("x", 10000) + "," + strings.Repeat("y", 10000) + "\n", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := csv.NewReader(strings.NewReader(tt.raw))
+			cr.FieldsPerRecord = -1
+			tk := NewTokenizer(strings.NewReader(tt.raw))
+			if tt.comma != 0 {
+				cr.Comma = rune(tt.comma)
+				tk.Comma = tt.comma
+			}
+			want, err := cr.ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got [][]string
+			for {
+				record, err := tk.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Read() error = %v", err)
+				}
+				got = append(got, record)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Read() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestTokenizer_options(t *testing.T) {
+	tk := NewTokenizer(strings.NewReader("# comment\n'a;b';'c''d'\n"))
+	tk.Comma, tk.Quote, tk.Comment = ';', '\'', '#'
+	got, err := tk.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if want := []string{"a;b", "c'd"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %q, want %q", got, want)
+	}
+}
+
+func TestTokenizer_quoteError(t *testing.T) {
+	for _, raw := range []string{`"a"b,c`, `"a,b`} {
+		tk := NewTokenizer(strings.NewReader(raw))
+		_, err := tk.Read()
+		var perr *csv.ParseError
+		if !errors.As(err, &perr) || perr.Err != csv.ErrQuote {
+			t.Errorf("Read(%q) error = %v, want *csv.ParseError of csv.ErrQuote", raw, err)
+		}
+	}
+}
+
+func TestTokenizer_compat(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		lazy   bool
+		trim   bool
+		fields int
+	}{
+		{"bare quote", `1,ab"c` + "\n", false, false, 0},
+		{"bare quote lazy", `1,ab"c` + "\n", true, false, 0},
+		{"quote after quoted field", `"a"b,c` + "\n", false, false, 0},
+		{"quote after quoted field lazy", `"a"b,c` + "\n", true, false, 0},
+		{"missing quote", "1,\"a\nb", false, false, 0},
+		{"missing quote lazy", "1,\"a\nb", true, false, 0},
+		{"cr at eof", `1,"x"` + "\r", false, false, 0},
+		{"cr at eof unquoted", "1,x\r", false, false, 0},
+		{"cr only", "1,x\n\r", false, false, 0},
+		{"error on later line", "1,a\n\n2,\"b\nc\"d\n", false, false, 0},
+		{"trim leading space", "1,  a, \"b\",\t\n", false, true, 0},
+		{"fields of first record", "1,a\n2\n3,c\n", false, false, 0},
+		{"fields per record", "1,a\n\"2\nx\",b,c\n", false, false, 2},
+		{"variable fields", "1,a\n2\n", false, false, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := csv.NewReader(strings.NewReader(tt.raw))
+			cr.LazyQuotes, cr.TrimLeadingSpace, cr.FieldsPerRecord = tt.lazy, tt.trim, tt.fields
+			tk := NewTokenizer(strings.NewReader(tt.raw))
+			tk.LazyQuotes, tk.TrimLeadingSpace, tk.FieldsPerRecord = tt.lazy, tt.trim, tt.fields
+
+			for {
+				want, werr := cr.Read()
+				got, err := tk.Read()
+				// csv.Reader returns a partial record with a parse error
+				if !reflect.DeepEqual(err, werr) || (werr == nil || errors.Is(werr, csv.ErrFieldCount)) && !reflect.DeepEqual(got, want) {
+					t.Fatalf("Read() = %q, %v, want %q, %v", got, err, want, werr)
+				}
+				if werr == io.EOF {
+					break
+				}
+				if werr != nil && !errors.Is(werr, csv.ErrFieldCount) {
+					break
+				}
+			}
+		})
+	}
+}
+
+type testTokenizerData struct {
+	Str   string    `csv:"0,str"`
+	Int   int       `csv:"1,int"`
+	Float float64   `csv:"2,float"`
+	Time  time.Time `csv:"3,time,2006-01-02T15:04:05 MST"`
+}
+
+func TestDecoder_tokenizer(t *testing.T) {
+	d, _ := NewDecoder(NewTokenizer(strings.NewReader(
+		"abc,1,2.5,2017-12-24T15:30:00 JST\nxyz,x,9.9,2018-01-01T00:00:00 PST\n")), false)
+
+	var v testTokenizerData
+	if err := d.Decode(&v); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	var v2 testTokenizerData
+	err := d.Decode(&v2)
+	var rerr *strconv.NumError
+	if !errors.As(err, &rerr) || rerr.Num != "x" {
+		t.Fatalf("Decode() error = %v, want NumError of x", err)
+	}
+	// cells retained by the previous value must not be overwritten
+	if v.Str != "abc" || v.Int != 1 || v.Float != 2.5 {
+		t.Errorf("Decode() = %+v", v)
+	}
+	if name, _ := v.Time.Zone(); name != "JST" {
+		t.Errorf("Decode() time zone = %v, want JST", name)
+	}
+}
@@ -0,0 +1,186 @@
+package csve
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// validation holds constraints given by tag options like min, max, minlen,
+// maxlen, pattern and oneof. It is compiled once in getFields.
+type validation struct {
+	min, max       *big.Rat
+	minText        string
+	maxText        string
+	minlen, maxlen int // -1 means no constraint
+	pattern        *regexp.Regexp
+	oneof          []string
+}
+
+func newValidation() *validation {
+	return &validation{minlen: -1, maxlen: -1}
+}
+
+func (o *tagOptions) validation() *validation {
+	if o.valid == nil {
+		o.valid = newValidation()
+	}
+	return o.valid
+}
+
+func (va *validation) numeric() bool {
+	return va.min != nil || va.max != nil
+}
+
+func (va *validation) textual() bool {
+	return va.minlen >= 0 || va.maxlen >= 0 || va.pattern != nil || va.oneof != nil
+}
+
+// compile checks if constraints are applicable to t.
+func (va *validation) compile(t reflect.Type) error {
+	t = validationType(t)
+	if va.numeric() && !isNumericType(t) {
+		return fmt.Errorf("min and max are not supported for %s", t)
+	}
+	if va.textual() && t.Kind() != reflect.String {
+		return fmt.Errorf("minlen, maxlen, pattern and oneof are not supported for %s", t)
+	}
+	return nil
+}
+
+// check returns the violated rule like "max=10". It returns empty string if v
+// satisfies all constraints. nil pointer and invalid sql.Null* are not checked.
+func (va *validation) check(v reflect.Value) string {
+	v, ok := validationValue(v)
+	if !ok {
+		return ""
+	}
+
+	if va.numeric() {
+		r := ratOf(v)
+		if r == nil {
+			return "finite"
+		}
+		if va.min != nil && r.Cmp(va.min) < 0 {
+			return "min=" + va.minText
+		}
+		if va.max != nil && r.Cmp(va.max) > 0 {
+			return "max=" + va.maxText
+		}
+	}
+
+	if va.textual() {
+		s := v.String()
+		n := utf8.RuneCountInString(s)
+		if va.minlen >= 0 && n < va.minlen {
+			return fmt.Sprintf("minlen=%d", va.minlen)
+		}
+		if va.maxlen >= 0 && n > va.maxlen {
+			return fmt.Sprintf("maxlen=%d", va.maxlen)
+		}
+		if va.pattern != nil && !va.pattern.MatchString(s) {
+			return "pattern=" + va.pattern.String()
+		}
+		if va.oneof != nil && !containsString(va.oneof, s) {
+			return "oneof=" + strings.Join(va.oneof, "|")
+		}
+	}
+	return ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validationType returns the type checked by validation, which is the type
+// pointed by pointer or held by sql.Null*.
+func validationType(t reflect.Type) reflect.Type {
+	for {
+		switch {
+		case t.Kind() == reflect.Ptr:
+			t = t.Elem()
+		case isSQLNullType(t):
+			t = t.Field(0).Type
+		default:
+			return t
+		}
+	}
+}
+
+func validationValue(v reflect.Value) (reflect.Value, bool) {
+	for {
+		switch {
+		case v.Kind() == reflect.Ptr:
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		case isSQLNullType(v.Type()):
+			if !v.Field(1).Bool() {
+				return v, false
+			}
+			v = v.Field(0)
+		default:
+			return v, true
+		}
+	}
+}
+
+func isNumericType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	switch t {
+	case bigIntType, bigFloatType, bigRatType, decimalType:
+		return true
+	}
+	return false
+}
+
+// ratOf returns numeric value v as big.Rat. It returns nil for NaN and Inf.
+func ratOf(v reflect.Value) *big.Rat {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Rat).SetUint64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return new(big.Rat).SetFloat64(v.Float())
+	}
+	switch v.Type() {
+	case bigIntType:
+		return new(big.Rat).SetInt(addr(v).Interface().(*big.Int))
+	case bigFloatType:
+		f := addr(v).Interface().(*big.Float)
+		if f.IsInf() {
+			return nil
+		}
+		r, _ := f.Rat(nil)
+		return r
+	case bigRatType:
+		return new(big.Rat).Set(addr(v).Interface().(*big.Rat))
+	case decimalType:
+		return v.Interface().(Decimal).Rat()
+	}
+	return nil
+}
+
+func parseBound(value string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, errors.New("invalid number")
+	}
+	return r, nil
+}
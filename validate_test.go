@@ -0,0 +1,82 @@
+package csve
+
+import (
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type TestValidationData struct {
+	Qty    int      `csv:"0,qty,,min=1,max=100"`
+	Price  *Decimal `csv:"1,price,,min=0.01"`
+	Code   string   `csv:"2,code,,pattern=^[A-Z]{2,3}$"`
+	Status string   `csv:"3,status,,oneof=active|inactive"`
+	Name   string   `csv:"4,name,,minlen=1,maxlen=5"`
+}
+
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		wantRule string
+	}{
+		{"valid", "1,0.01,JP,active,すずき", ""},
+		{"valid nil pointer", "100,,USA,inactive,a", ""},
+		{"min", "0,1,JP,active,a", "min=1"},
+		{"max", "101,1,JP,active,a", "max=100"},
+		{"decimal min", "1,0.00,JP,active,a", "min=0.01"},
+		{"pattern", "1,1,JAPN,active,a", "pattern=^[A-Z]{2,3}$"},
+		{"oneof", "1,1,JP,deleted,a", "oneof=active|inactive"},
+		{"minlen", "1,1,JP,active,", "minlen=1"},
+		{"maxlen", "1,1,JP,active,abcdef", "maxlen=5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := NewDecoder(csv.NewReader(strings.NewReader(tt.raw)), false)
+			var v TestValidationData
+			err := d.Decode(&v)
+			if tt.wantRule == "" {
+				if err != nil {
+					t.Errorf("Decode() error = %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Decode() error = %v, want ValidationError", err)
+			}
+			if verr.Rule != tt.wantRule {
+				t.Errorf("ValidationError.Rule = %v, want %v", verr.Rule, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestValidation_compile(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"min on string", struct {
+			V string `csv:"0,v,,min=1"`
+		}{}},
+		{"pattern on int", struct {
+			V int `csv:"0,v,,pattern=^1$"`
+		}{}},
+		{"invalid pattern", struct {
+			V string `csv:"0,v,,pattern=("`
+		}{}},
+		{"invalid min", struct {
+			V int `csv:"0,v,,min=x"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := getFields(reflect.TypeOf(tt.v)); err == nil {
+				t.Errorf("getFields() error = nil, want error")
+			}
+		})
+	}
+}
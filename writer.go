@@ -0,0 +1,95 @@
+package csve
+
+import (
+	"bufio"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// quoteWriter writes csv like csv.Writer with a quote other than '"', which
+// csv.Writer does not support.
+type quoteWriter struct {
+	Comma   byte
+	Quote   byte
+	UseCRLF bool
+
+	w *bufio.Writer
+}
+
+func (w *quoteWriter) Write(record []string) error {
+	for i, field := range record {
+		if i > 0 {
+			w.w.WriteByte(w.Comma)
+		}
+		if !w.fieldNeedsQuotes(field) {
+			w.w.WriteString(field)
+			continue
+		}
+
+		w.w.WriteByte(w.Quote)
+		for len(field) > 0 {
+			i := strings.IndexAny(field, string([]byte{w.Quote, '\r', '\n'}))
+			if i < 0 {
+				i = len(field)
+			}
+			w.w.WriteString(field[:i])
+			field = field[i:]
+			if len(field) == 0 {
+				break
+			}
+			switch field[0] {
+			case w.Quote:
+				w.w.WriteByte(w.Quote)
+				w.w.WriteByte(w.Quote)
+			case '\r':
+				if !w.UseCRLF {
+					w.w.WriteByte('\r')
+				}
+			case '\n':
+				if w.UseCRLF {
+					w.w.WriteString("\r\n")
+				} else {
+					w.w.WriteByte('\n')
+				}
+			}
+			field = field[1:]
+		}
+		w.w.WriteByte(w.Quote)
+	}
+
+	var err error
+	if w.UseCRLF {
+		_, err = w.w.WriteString("\r\n")
+	} else {
+		err = w.w.WriteByte('\n')
+	}
+	return err
+}
+
+func (w *quoteWriter) Flush() {
+	w.w.Flush()
+}
+
+// Error returns an error of a previous Write or Flush.
+func (w *quoteWriter) Error() error {
+	_, err := w.w.Write(nil)
+	return err
+}
+
+// fieldNeedsQuotes reports whether field must be quoted, the same way as
+// csv.Writer.
+func (w *quoteWriter) fieldNeedsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` {
+		return true
+	}
+	if strings.IndexByte(field, w.Comma) >= 0 || strings.IndexByte(field, w.Quote) >= 0 ||
+		strings.ContainsAny(field, "\r\n") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r)
+}